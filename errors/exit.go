@@ -55,6 +55,15 @@ func Exit(code int) {
 	osExit(code)
 }
 
+// ExitMessage calls the exit hook (if set) with the message and then exits the program with
+// the given code, the message is not printed, e.g. because it has already been logged.
+func ExitMessage(code int, msg string) {
+	if exitHook != nil {
+		exitHook(code, msg, GetTrace(3))
+	}
+	osExit(code)
+}
+
 // Exitf prints a formatted error message to the error output, calls the exit hook (if set),
 // and then exits the program with the given code.
 func Exitf(code int, format string, args ...any) {
@@ -24,6 +24,26 @@ func TestExit(t *testing.T) {
 	require.Equal(t, wantExitCode, actualExitCode)
 }
 
+func TestExitMessage(t *testing.T) {
+	actualExitCode := 0
+	defer ReplaceExit(func(code int) {
+		actualExitCode = code
+	})()
+	var hookMessage string
+	SetExitHook(func(code int, msg string, tracer Tracer) {
+		hookMessage = msg
+		require.Equal(t, 3, code)
+		require.NotNil(t, tracer)
+	})
+	defer SetExitHook(nil)
+	buf := &bytes.Buffer{}
+	SetErrOutput(buf)
+	ExitMessage(3, "logged")
+	require.Equal(t, 3, actualExitCode)
+	require.Equal(t, "logged", hookMessage)
+	require.Empty(t, buf.String())
+}
+
 func TestExitf(t *testing.T) {
 
 	// mock exit function
@@ -0,0 +1,208 @@
+package log
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/stkali/utility/errors"
+)
+
+// OverflowPolicy decides what an AsyncWriter does when its queue is full.
+type OverflowPolicy int
+
+const (
+	// Block waits until the background flusher frees a slot in the queue.
+	Block OverflowPolicy = iota
+	// DropNewest discards the message being written.
+	DropNewest
+	// DropOldest discards the oldest queued message to make room for the new one.
+	DropOldest
+)
+
+// defaultQueueSize is the queue size used when NewAsyncWriter is given a size <= 0.
+const defaultQueueSize = 1024
+
+var (
+	// WriterClosedError is returned when writing to a closed AsyncWriter.
+	WriterClosedError = errors.Error("write to closed async writer")
+)
+
+// Flusher is implemented by writers that buffer data, the logger flushes its output before
+// FATAL exits the program.
+type Flusher interface {
+	Flush() error
+}
+
+// AsyncWriter is an io.Writer that queues the written data in a bounded ring buffer and
+// writes it to the underlying writer from a background goroutine, so that a slow writer
+// does not stall the goroutines that log.
+type AsyncWriter struct {
+	writer io.Writer
+	policy OverflowPolicy
+
+	mtx sync.Mutex
+	// notEmpty is signaled when a message is queued or the writer is closed.
+	notEmpty *sync.Cond
+	// notFull is signaled when the flusher takes messages out of the queue.
+	notFull *sync.Cond
+	// idle is signaled when the flusher has written a batch of messages.
+	idle *sync.Cond
+
+	// queue is the ring buffer, head is the index of the oldest message and size the
+	// number of queued messages.
+	queue [][]byte
+	head  int
+	size  int
+	// enqueued counts the queued messages and finished the messages that have been written
+	// or discarded by DropOldest, Flush waits for finished to reach enqueued.
+	enqueued uint64
+	finished uint64
+	// err is the first write error since the last Flush.
+	err    error
+	closed bool
+	done   chan struct{}
+
+	// dropped counts the discarded messages, it is accessed atomically.
+	dropped uint64
+}
+
+var _ io.Writer = (*AsyncWriter)(nil)
+var _ Flusher = (*AsyncWriter)(nil)
+
+// NewAsyncWriter returns an AsyncWriter that writes to writer with a queue of size messages
+// (defaultQueueSize if size <= 0) and starts its background flusher.
+func NewAsyncWriter(writer io.Writer, size int, policy OverflowPolicy) *AsyncWriter {
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	w := &AsyncWriter{
+		writer: writer,
+		policy: policy,
+		queue:  make([][]byte, size),
+		done:   make(chan struct{}),
+	}
+	w.notEmpty = sync.NewCond(&w.mtx)
+	w.notFull = sync.NewCond(&w.mtx)
+	w.idle = sync.NewCond(&w.mtx)
+	go w.run()
+	return w
+}
+
+// Write implements the io.Writer interface. It copies b into the queue and returns
+// immediately unless the queue is full and the policy is Block. Discarded messages are
+// reported as written and counted by Dropped.
+func (w *AsyncWriter) Write(b []byte) (int, error) {
+	msg := make([]byte, len(b))
+	copy(msg, b)
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.closed {
+		return 0, WriterClosedError
+	}
+	if w.size == len(w.queue) {
+		switch w.policy {
+		case DropNewest:
+			atomic.AddUint64(&w.dropped, 1)
+			return len(b), nil
+		case DropOldest:
+			w.queue[w.head] = nil
+			w.head = (w.head + 1) % len(w.queue)
+			w.size--
+			w.finished++
+			atomic.AddUint64(&w.dropped, 1)
+		default:
+			for w.size == len(w.queue) && !w.closed {
+				w.notFull.Wait()
+			}
+			if w.closed {
+				return 0, WriterClosedError
+			}
+		}
+	}
+	w.queue[(w.head+w.size)%len(w.queue)] = msg
+	w.size++
+	w.enqueued++
+	w.notEmpty.Signal()
+	return len(b), nil
+}
+
+// run is the background flusher, it writes the queued messages until the writer is closed
+// and the queue is drained.
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	batch := make([][]byte, 0, len(w.queue))
+	for {
+		w.mtx.Lock()
+		for w.size == 0 && !w.closed {
+			w.notEmpty.Wait()
+		}
+		if w.size == 0 {
+			w.mtx.Unlock()
+			return
+		}
+		batch = batch[:0]
+		for ; w.size > 0; w.size-- {
+			batch = append(batch, w.queue[w.head])
+			w.queue[w.head] = nil
+			w.head = (w.head + 1) % len(w.queue)
+		}
+		w.notFull.Broadcast()
+		w.mtx.Unlock()
+
+		var err error
+		for index := range batch {
+			if _, e := w.writer.Write(batch[index]); e != nil && err == nil {
+				err = e
+			}
+			batch[index] = nil
+		}
+
+		w.mtx.Lock()
+		if err != nil && w.err == nil {
+			w.err = err
+		}
+		w.finished += uint64(len(batch))
+		w.idle.Broadcast()
+		w.mtx.Unlock()
+	}
+}
+
+// Dropped returns the number of messages discarded by the overflow policy.
+func (w *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Flush waits until all the messages queued before the call have been written and flushes
+// the underlying writer if it implements Flusher. It returns the first write error since
+// the last Flush.
+func (w *AsyncWriter) Flush() error {
+	w.mtx.Lock()
+	for target := w.enqueued; w.finished < target; {
+		w.idle.Wait()
+	}
+	err := w.err
+	w.err = nil
+	w.mtx.Unlock()
+	if flusher, ok := w.writer.(Flusher); ok {
+		err = errors.Join(err, flusher.Flush())
+	}
+	return err
+}
+
+// Close stops accepting messages, drains the queue and stops the background flusher.
+// The underlying writer is flushed but not closed, subsequent calls to Close return nil.
+func (w *AsyncWriter) Close() error {
+	w.mtx.Lock()
+	if w.closed {
+		w.mtx.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.notEmpty.Broadcast()
+	w.notFull.Broadcast()
+	w.mtx.Unlock()
+	<-w.done
+	return w.Flush()
+}
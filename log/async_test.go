@@ -0,0 +1,179 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// slowWriter records the written data and blocks every write until release is closed.
+type slowWriter struct {
+	mtx     sync.Mutex
+	buf     bytes.Buffer
+	release chan struct{}
+	err     error
+	flushed int
+}
+
+func newSlowWriter() *slowWriter {
+	return &slowWriter{release: make(chan struct{})}
+}
+
+func (w *slowWriter) Write(b []byte) (int, error) {
+	<-w.release
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.buf.Write(b)
+	return len(b), w.err
+}
+
+func (w *slowWriter) Flush() error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.flushed++
+	return nil
+}
+
+func (w *slowWriter) String() string {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.buf.String()
+}
+
+// fill writes messages to w until the queue is full and the flusher is blocked by the
+// slow writer holding the first message.
+func fill(t *testing.T, w *AsyncWriter, size int) {
+	t.Helper()
+	_, err := w.Write([]byte("0"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		w.mtx.Lock()
+		defer w.mtx.Unlock()
+		return w.size == 0
+	}, time.Second, time.Millisecond)
+	for i := 1; i <= size; i++ {
+		_, err = w.Write([]byte(strconv.Itoa(i)))
+		require.NoError(t, err)
+	}
+}
+
+func TestAsyncWriter(t *testing.T) {
+	t.Run("write and flush", func(t *testing.T) {
+		slow := newSlowWriter()
+		close(slow.release)
+		w := NewAsyncWriter(slow, 0, Block)
+		require.Equal(t, defaultQueueSize, len(w.queue))
+		b := []byte("hello\n")
+		n, err := w.Write(b)
+		require.NoError(t, err)
+		require.Equal(t, len(b), n)
+		// the data is copied
+		b[0] = 'j'
+		require.NoError(t, w.Flush())
+		require.Equal(t, "hello\n", slow.String())
+		require.Equal(t, 1, slow.flushed)
+		require.NoError(t, w.Close())
+		require.NoError(t, w.Close())
+		_, err = w.Write(b)
+		require.ErrorIs(t, err, WriterClosedError)
+	})
+
+	t.Run("drop newest", func(t *testing.T) {
+		slow := newSlowWriter()
+		w := NewAsyncWriter(slow, 2, DropNewest)
+		fill(t, w, 4)
+		require.Equal(t, uint64(2), w.Dropped())
+		close(slow.release)
+		require.NoError(t, w.Close())
+		require.Equal(t, "012", slow.String())
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		slow := newSlowWriter()
+		w := NewAsyncWriter(slow, 2, DropOldest)
+		fill(t, w, 4)
+		require.Equal(t, uint64(2), w.Dropped())
+		close(slow.release)
+		require.NoError(t, w.Flush())
+		require.Equal(t, "034", slow.String())
+		require.NoError(t, w.Close())
+	})
+
+	t.Run("block", func(t *testing.T) {
+		slow := newSlowWriter()
+		w := NewAsyncWriter(slow, 2, Block)
+		fill(t, w, 2)
+		written := make(chan struct{})
+		go func() {
+			defer close(written)
+			_, _ = w.Write([]byte("3"))
+		}()
+		select {
+		case <-written:
+			t.Fatal("write to a full queue must block")
+		case <-time.After(50 * time.Millisecond):
+		}
+		close(slow.release)
+		<-written
+		require.NoError(t, w.Close())
+		require.Equal(t, "0123", slow.String())
+		require.Equal(t, uint64(0), w.Dropped())
+	})
+
+	t.Run("close blocked writer", func(t *testing.T) {
+		slow := newSlowWriter()
+		w := NewAsyncWriter(slow, 1, Block)
+		fill(t, w, 1)
+		errs := make(chan error)
+		go func() {
+			_, err := w.Write([]byte("2"))
+			errs <- err
+		}()
+		time.Sleep(10 * time.Millisecond)
+		closed := make(chan error)
+		go func() {
+			closed <- w.Close()
+		}()
+		require.ErrorIs(t, <-errs, WriterClosedError)
+		close(slow.release)
+		require.NoError(t, <-closed)
+		require.Equal(t, "01", slow.String())
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		slow := newSlowWriter()
+		slow.err = io.ErrShortWrite
+		close(slow.release)
+		w := NewAsyncWriter(slow, 1, Block)
+		_, err := w.Write([]byte("a"))
+		require.NoError(t, err)
+		require.ErrorIs(t, w.Flush(), io.ErrShortWrite)
+		require.NoError(t, w.Flush())
+		require.NoError(t, w.Close())
+	})
+}
+
+func TestFatalFlush(t *testing.T) {
+	slow := newSlowWriter()
+	close(slow.release)
+	w := NewAsyncWriter(slow, 16, Block)
+	defer w.Close()
+	SetOutput(w)
+	SetFlags(0)
+	SetPrefix("")
+	defer SetOutput(os.Stdout)
+
+	var output string
+	defer func(exit func(int)) { Exit = exit }(Exit)
+	Exit = func(code int) {
+		output = slow.String()
+	}
+	Fatal("bye")
+	require.Equal(t, FATAL.String()+"bye\n", output)
+}
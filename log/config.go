@@ -0,0 +1,487 @@
+package log
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stkali/utility/errors"
+	"github.com/stkali/utility/lib"
+	"github.com/stkali/utility/rotate"
+)
+
+// EnvPrefix is the prefix of the environment variables read by Config.LoadEnv.
+const EnvPrefix = "UTILITY_LOG_"
+
+// Config is the declarative configuration of the standard logger, see Configure.
+// It can be read from a JSON file with ReadConfig and overridden by environment variables
+// with LoadEnv:
+//
+//	{
+//	  "level": "info",
+//	  "levels": "storage=debug",
+//	  "format": "json",
+//	  "flags": "std,shortfile",
+//	  "outputs": [
+//	    {"type": "stderr", "format": "console"},
+//	    {"type": "file", "path": "/var/log/app.log", "level": "warn",
+//	     "rotate": {"max_size": "100 MB", "duration": "24h", "backups": 7}}
+//	  ]
+//	}
+type Config struct {
+	// Level(default: WARN) is the level of the logger, see ToLevel.
+	Level string `json:"level"`
+	// Levels are the level rules of the named loggers, see SetLevels.
+	Levels string `json:"levels"`
+	// Format(default: "text") is the encoder of the logger: "text", "json" or "console".
+	Format string `json:"format"`
+	// Flags(default: "std,microseconds,shortfile") is a comma-separated list of the flags
+	// of the logger: "date", "time", "microseconds", "longfile", "shortfile", "utc",
+	// "msgprefix", "function", "std" (date and time) or "none".
+	Flags string `json:"flags"`
+	// Prefix is the prefix of the logger.
+	Prefix string `json:"prefix"`
+	// Outputs(default: stdout) are the destinations of the entries.
+	Outputs []OutputConfig `json:"outputs"`
+}
+
+// OutputConfig is a destination of the entries.
+type OutputConfig struct {
+	// Type is "stdout", "stderr" or "file".
+	Type string `json:"type"`
+	// Path is the file written by the "file" type.
+	Path string `json:"path"`
+	// Level is the minimum level of the entries written to the output, empty means all the
+	// entries enabled by the logger.
+	Level string `json:"level"`
+	// Format is the encoder of the output, empty means the format of the logger.
+	Format string `json:"format"`
+	// Rotate rotates the file written by the "file" type, nil means no rotation.
+	Rotate *RotateConfig `json:"rotate"`
+}
+
+// RotateConfig holds the rotate.Option fields of a rotating file output, empty fields
+// keep the defaults of the rotate package.
+type RotateConfig struct {
+	// MaxSize, MaxTotalSize and MinFreeSpace are parsed by lib.String2Size, e.g. "100 MB".
+	MaxSize      string `json:"max_size"`
+	MaxTotalSize string `json:"max_total_size"`
+	MinFreeSpace string `json:"min_free_space"`
+	// Duration and MaxAge are parsed by time.ParseDuration, e.g. "24h".
+	Duration string `json:"duration"`
+	MaxAge   string `json:"max_age"`
+	// Schedule is parsed by rotate.ParseSchedule, e.g. "daily" or "0 * * * *", it replaces
+	// Duration.
+	Schedule string `json:"schedule"`
+	// Location is the IANA time zone of Schedule, e.g. "UTC", empty means the local time.
+	Location string `json:"location"`
+	Backups  *int   `json:"backups"`
+	// CompressLevel is the gzip level of the backups, 0 disables the compression.
+	CompressLevel *int   `json:"compress_level"`
+	BackupPrefix  string `json:"backup_prefix"`
+	// BackupTemplate names the backups, e.g. rotate.LogrotateTemplate "{name}{ext}.{index}".
+	BackupTemplate string `json:"backup_template"`
+	// ModePerm is the octal permission of the file, e.g. "0644".
+	ModePerm string `json:"mode_perm"`
+}
+
+// ReadConfig reads a JSON configuration file.
+func ReadConfig(file string) (*Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Newf("failed to read log config %q, err: %s", file, err)
+	}
+	cfg := &Config{}
+	if err = json.Unmarshal(data, cfg); err != nil {
+		return nil, errors.Newf("failed to parse log config %q, err: %s", file, err)
+	}
+	return cfg, nil
+}
+
+// LoadEnv overrides the configuration with the environment variables that are set:
+//   - UTILITY_LOG_LEVEL, UTILITY_LOG_LEVELS, UTILITY_LOG_FORMAT, UTILITY_LOG_FLAGS and
+//     UTILITY_LOG_PREFIX override the fields of the same name,
+//   - UTILITY_LOG_OUTPUT replaces the outputs with a comma-separated list of "stdout",
+//     "stderr" or file paths,
+//   - UTILITY_LOG_MAX_SIZE, UTILITY_LOG_MAX_TOTAL_SIZE, UTILITY_LOG_MIN_FREE_SPACE,
+//     UTILITY_LOG_DURATION, UTILITY_LOG_MAX_AGE, UTILITY_LOG_SCHEDULE,
+//     UTILITY_LOG_LOCATION, UTILITY_LOG_BACKUPS and UTILITY_LOG_COMPRESS_LEVEL override
+//     the rotation of the file outputs.
+func (c *Config) LoadEnv() error {
+	for name, field := range map[string]*string{
+		"LEVEL":  &c.Level,
+		"LEVELS": &c.Levels,
+		"FORMAT": &c.Format,
+		"FLAGS":  &c.Flags,
+		"PREFIX": &c.Prefix,
+	} {
+		if value, ok := os.LookupEnv(EnvPrefix + name); ok {
+			*field = value
+		}
+	}
+	if value, ok := os.LookupEnv(EnvPrefix + "OUTPUT"); ok {
+		// a new slice, the outputs may be shared with a copy of the configuration
+		var outputs []OutputConfig
+		for _, item := range strings.Split(value, ",") {
+			switch item = strings.TrimSpace(item); item {
+			case "":
+			case "stdout", "stderr":
+				outputs = append(outputs, OutputConfig{Type: item})
+			default:
+				outputs = append(outputs, OutputConfig{Type: "file", Path: item})
+			}
+		}
+		c.Outputs = outputs
+	}
+	rotation := RotateConfig{}
+	rotated := false
+	for name, field := range map[string]*string{
+		"MAX_SIZE":       &rotation.MaxSize,
+		"MAX_TOTAL_SIZE": &rotation.MaxTotalSize,
+		"MIN_FREE_SPACE": &rotation.MinFreeSpace,
+		"DURATION":       &rotation.Duration,
+		"MAX_AGE":        &rotation.MaxAge,
+		"SCHEDULE":       &rotation.Schedule,
+		"LOCATION":       &rotation.Location,
+	} {
+		if value, ok := os.LookupEnv(EnvPrefix + name); ok {
+			*field, rotated = value, true
+		}
+	}
+	for name, field := range map[string]**int{
+		"BACKUPS":        &rotation.Backups,
+		"COMPRESS_LEVEL": &rotation.CompressLevel,
+	} {
+		if value, ok := os.LookupEnv(EnvPrefix + name); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return errors.Newf("invalid %s%s %q, err: %s", EnvPrefix, name, value, err)
+			}
+			*field, rotated = &n, true
+		}
+	}
+	if rotated {
+		c.Outputs = append([]OutputConfig(nil), c.Outputs...)
+		for index := range c.Outputs {
+			output := &c.Outputs[index]
+			if output.Type != "file" {
+				continue
+			}
+			merged := RotateConfig{}
+			if output.Rotate != nil {
+				merged = *output.Rotate
+			}
+			merged.merge(&rotation)
+			output.Rotate = &merged
+		}
+	}
+	return nil
+}
+
+// merge overrides the fields of r with the fields set in other.
+func (r *RotateConfig) merge(other *RotateConfig) {
+	if other.MaxSize != "" {
+		r.MaxSize = other.MaxSize
+	}
+	if other.MaxTotalSize != "" {
+		r.MaxTotalSize = other.MaxTotalSize
+	}
+	if other.MinFreeSpace != "" {
+		r.MinFreeSpace = other.MinFreeSpace
+	}
+	if other.Duration != "" {
+		r.Duration = other.Duration
+	}
+	if other.MaxAge != "" {
+		r.MaxAge = other.MaxAge
+	}
+	if other.Schedule != "" {
+		r.Schedule = other.Schedule
+	}
+	if other.Location != "" {
+		r.Location = other.Location
+	}
+	if other.Backups != nil {
+		r.Backups = other.Backups
+	}
+	if other.CompressLevel != nil {
+		r.CompressLevel = other.CompressLevel
+	}
+	if other.BackupPrefix != "" {
+		r.BackupPrefix = other.BackupPrefix
+	}
+	if other.BackupTemplate != "" {
+		r.BackupTemplate = other.BackupTemplate
+	}
+	if other.ModePerm != "" {
+		r.ModePerm = other.ModePerm
+	}
+}
+
+// options returns the rotate options of the configuration.
+func (r *RotateConfig) options() ([]rotate.SetOption, error) {
+	var opts []rotate.SetOption
+	if r.MaxSize != "" {
+		size, err := lib.String2Size(r.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, rotate.WithMaxSize(size))
+	}
+	if r.MaxTotalSize != "" {
+		opts = append(opts, rotate.WithMaxTotalSizeString(r.MaxTotalSize))
+	}
+	if r.MinFreeSpace != "" {
+		opts = append(opts, rotate.WithMinFreeSpaceString(r.MinFreeSpace))
+	}
+	if r.Duration != "" {
+		duration, err := time.ParseDuration(r.Duration)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, rotate.WithDuration(duration))
+	}
+	if r.MaxAge != "" {
+		age, err := time.ParseDuration(r.MaxAge)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, rotate.WithMaxAge(age))
+	}
+	if r.Schedule != "" {
+		schedule, err := rotate.ParseSchedule(r.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, rotate.WithSchedule(schedule))
+	}
+	if r.Location != "" {
+		loc, err := time.LoadLocation(r.Location)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, rotate.WithLocation(loc))
+	}
+	if r.Backups != nil {
+		opts = append(opts, rotate.WithBackups(*r.Backups))
+	}
+	if r.CompressLevel != nil {
+		opts = append(opts, rotate.WithCompressLevel(*r.CompressLevel))
+	}
+	if r.BackupPrefix != "" {
+		opts = append(opts, rotate.WithBackupPrefix(r.BackupPrefix))
+	}
+	if r.BackupTemplate != "" {
+		opts = append(opts, rotate.WithBackupTemplate(r.BackupTemplate))
+	}
+	if r.ModePerm != "" {
+		perm, err := strconv.ParseUint(r.ModePerm, 8, 32)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, rotate.WithModePerm(os.FileMode(perm)))
+	}
+	return opts, nil
+}
+
+// flagNames maps the names accepted by Config.Flags to the flags.
+var flagNames = map[string]int{
+	"date":         Ldate,
+	"time":         Ltime,
+	"microseconds": Lmicroseconds,
+	"longfile":     Llongfile,
+	"shortfile":    Lshortfile,
+	"utc":          LUTC,
+	"msgprefix":    Lmsgprefix,
+	"function":     Lfuncname,
+	"std":          LstdFlags,
+	"none":         0,
+}
+
+// ParseFlags parses a comma-separated list of flag names, see Config.Flags.
+func ParseFlags(spec string) (int, error) {
+	flags := 0
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		flag, ok := flagNames[name]
+		if !ok {
+			return 0, errors.Newf("invalid log flag %q", name)
+		}
+		flags |= flag
+	}
+	return flags, nil
+}
+
+// newEncoder returns the encoder of the format, the console encoder detects the colors
+// of writer.
+func newEncoder(format string, writer io.Writer) (Encoder, error) {
+	switch strings.ToLower(format) {
+	case "", "text":
+		return NewTextEncoder(), nil
+	case "json":
+		return NewJSONEncoder(), nil
+	case "console":
+		return NewConsoleEncoder(writer), nil
+	default:
+		return nil, errors.Newf("invalid log format %q", format)
+	}
+}
+
+// parseConfigLevel parses the level of a configuration, empty means def.
+func parseConfigLevel(level string, def Level) (Level, error) {
+	if level == "" {
+		return def, nil
+	}
+	lv, ok := parseLevel(level)
+	if !ok {
+		return 0, errors.Newf("invalid log level %q", level)
+	}
+	return lv, nil
+}
+
+// openOutput opens the writer of the output, the returned closer is nil for the
+// standard streams.
+func openOutput(output *OutputConfig) (io.Writer, io.Closer, error) {
+	switch strings.ToLower(output.Type) {
+	case "stdout":
+		return os.Stdout, nil, nil
+	case "stderr":
+		return os.Stderr, nil, nil
+	case "file":
+		if output.Path == "" {
+			return nil, nil, errors.Error("file output without path")
+		}
+		if output.Rotate == nil {
+			file, err := os.OpenFile(output.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				return nil, nil, err
+			}
+			return file, file, nil
+		}
+		opts, err := output.Rotate.options()
+		if err != nil {
+			return nil, nil, errors.Newf("invalid rotation of %q, err: %s", output.Path, err)
+		}
+		file, err := rotate.NewRotatingFile(output.Path, opts...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return file, file, nil
+	default:
+		return nil, nil, errors.Newf("invalid log output type %q", output.Type)
+	}
+}
+
+var (
+	// configuredMtx serializes Configure.
+	configuredMtx sync.Mutex
+	// configuredClosers are the files opened by the last Configure, they are closed by the
+	// next Configure and before FATAL exits the program.
+	configuredClosers []io.Closer
+	// removeFatalCallback removes the OnFatal callback of the last Configure.
+	removeFatalCallback func()
+)
+
+// Configure applies the configuration to the standard logger: its output, encoder, flags,
+// prefix and level are replaced in place, so that the loggers created before by With or
+// Named follow it, and the level rules of the named loggers are set with SetLevels. A new
+// logger is installed with SetLogger if the logger set by SetLogger is not the one of this
+// package. The files opened by the previous Configure are flushed and closed once the
+// writes in flight on them are done, the files opened by this one are closed before FATAL
+// exits the program. The current configuration is kept if the configuration is invalid.
+func Configure(cfg *Config) error {
+	level, err := parseConfigLevel(cfg.Level, defaultLevel)
+	if err != nil {
+		return err
+	}
+	if _, err = ParseLevels(cfg.Levels); err != nil {
+		return err
+	}
+	flags := defaultFlags
+	if cfg.Flags != "" {
+		if flags, err = ParseFlags(cfg.Flags); err != nil {
+			return err
+		}
+	}
+	outputs := cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = []OutputConfig{{Type: "stdout"}}
+	}
+
+	var closers []io.Closer
+	closeAll := func() {
+		for _, closer := range closers {
+			errors.Warning(closer.Close())
+		}
+	}
+	sinks := make([]Sink, 0, len(outputs))
+	for index := range outputs {
+		output := &outputs[index]
+		writer, closer, err := openOutput(output)
+		if err != nil {
+			closeAll()
+			return err
+		}
+		if closer != nil {
+			closers = append(closers, closer)
+		}
+		sink := Sink{Writer: writer}
+		format := output.Format
+		if format == "" {
+			format = cfg.Format
+		}
+		// every output has its own encoder, the console encoder detects the colors of its writer
+		if sink.Level, err = parseConfigLevel(output.Level, TRACE); err == nil {
+			sink.Encoder, err = newEncoder(format, writer)
+		}
+		if err != nil {
+			closeAll()
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	out, encoder := sinks[0].Writer, sinks[0].Encoder
+	if len(sinks) > 1 || sinks[0].Level != TRACE {
+		out = NewTee(sinks...)
+	}
+
+	configuredMtx.Lock()
+	defer configuredMtx.Unlock()
+	if l, ok := loadLogger().(*defaultLogger); ok {
+		l.core.reset(out, cfg.Prefix, flags, encoder)
+		l.SetLevel(level)
+	} else {
+		SetLogger(&defaultLogger{
+			level: newLevel(level),
+			core:  newCore(out, cfg.Prefix, flags, encoder),
+		})
+	}
+	errors.Warning(SetLevels(cfg.Levels))
+	previous := configuredClosers
+	configuredClosers = closers
+	if removeFatalCallback != nil {
+		removeFatalCallback()
+		removeFatalCallback = nil
+	}
+	if len(closers) > 0 {
+		removeFatalCallback = OnFatal(func() error {
+			var err error
+			for _, closer := range closers {
+				err = errors.Join(err, closer.Close())
+			}
+			return err
+		})
+	}
+	for _, closer := range previous {
+		errors.Warning(closer.Close())
+	}
+	return nil
+}
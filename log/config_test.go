@@ -0,0 +1,207 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// saveConfigured saves the settings of the standard logger, the returned function closes
+// the files opened by Configure and restores them.
+func saveConfigured() (restore func()) {
+	origin := DefaultLogger().(*defaultLogger)
+	c := origin.core
+	c.mtx.Lock()
+	out, prefix, flags, encoder := c.out, c.prefix, c.flags, c.encoder
+	c.mtx.Unlock()
+	level := origin.GetLevel()
+	return func() {
+		configuredMtx.Lock()
+		defer configuredMtx.Unlock()
+		for _, closer := range configuredClosers {
+			_ = closer.Close()
+		}
+		configuredClosers = nil
+		if removeFatalCallback != nil {
+			removeFatalCallback()
+			removeFatalCallback = nil
+		}
+		SetLogger(origin)
+		c.reset(out, prefix, flags, encoder)
+		origin.SetLevel(level)
+		_ = SetLevels("")
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	flags, err := ParseFlags("std, shortfile,UTC,function")
+	require.NoError(t, err)
+	require.Equal(t, LstdFlags|Lshortfile|LUTC|Lfuncname, flags)
+
+	flags, err = ParseFlags("none")
+	require.NoError(t, err)
+	require.Equal(t, 0, flags)
+
+	_, err = ParseFlags("date,week")
+	require.Error(t, err)
+}
+
+func TestReadConfig(t *testing.T) {
+	folder := t.TempDir()
+	file := filepath.Join(folder, "log.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{
+		"level": "info",
+		"format": "json",
+		"flags": "none",
+		"outputs": [
+			{"type": "stderr", "level": "error"},
+			{"type": "file", "path": "app.log", "rotate": {"max_size": "1 MB", "backups": 0}}
+		]
+	}`), 0o644))
+	cfg, err := ReadConfig(file)
+	require.NoError(t, err)
+	zero := 0
+	require.Equal(t, &Config{
+		Level:  "info",
+		Format: "json",
+		Flags:  "none",
+		Outputs: []OutputConfig{
+			{Type: "stderr", Level: "error"},
+			{Type: "file", Path: "app.log", Rotate: &RotateConfig{MaxSize: "1 MB", Backups: &zero}},
+		},
+	}, cfg)
+
+	_, err = ReadConfig(filepath.Join(folder, "not-exists.json"))
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(file, []byte(`{"level": 1}`), 0o644))
+	_, err = ReadConfig(file)
+	require.Error(t, err)
+}
+
+func TestConfigLoadEnv(t *testing.T) {
+	t.Setenv("UTILITY_LOG_LEVEL", "debug")
+	t.Setenv("UTILITY_LOG_FORMAT", "console")
+	t.Setenv("UTILITY_LOG_OUTPUT", "stderr, /var/log/app.log")
+	t.Setenv("UTILITY_LOG_MAX_SIZE", "10MB")
+	t.Setenv("UTILITY_LOG_MAX_TOTAL_SIZE", "1GB")
+	t.Setenv("UTILITY_LOG_BACKUPS", "3")
+	t.Setenv("UTILITY_LOG_SCHEDULE", "hourly")
+
+	cfg := &Config{Level: "info", Prefix: "app: ", Outputs: []OutputConfig{{Type: "stdout"}}}
+	require.NoError(t, cfg.LoadEnv())
+	three := 3
+	require.Equal(t, &Config{
+		Level:  "debug",
+		Format: "console",
+		Prefix: "app: ",
+		Outputs: []OutputConfig{
+			{Type: "stderr"},
+			{Type: "file", Path: "/var/log/app.log", Rotate: &RotateConfig{MaxSize: "10MB", MaxTotalSize: "1GB", Schedule: "hourly", Backups: &three}},
+		},
+	}, cfg)
+
+	// the outputs of the caller are not modified
+	outputs := make([]OutputConfig, 1, 4)
+	outputs[0] = OutputConfig{Type: "file", Path: "/var/log/old.log", Rotate: &RotateConfig{MaxAge: "24h"}}
+	shared := Config{Outputs: outputs}
+	require.NoError(t, (&Config{Outputs: outputs}).LoadEnv())
+	require.NoError(t, shared.LoadEnv())
+	require.Equal(t, OutputConfig{Type: "file", Path: "/var/log/old.log", Rotate: &RotateConfig{MaxAge: "24h"}}, outputs[0])
+	require.Equal(t, OutputConfig{}, outputs[:2][1])
+
+	t.Setenv("UTILITY_LOG_COMPRESS_LEVEL", "high")
+	require.Error(t, cfg.LoadEnv())
+}
+
+func TestConfigure(t *testing.T) {
+	origin := DefaultLogger()
+	defer saveConfigured()()
+	// the loggers created before, like the package-level loggers, follow the configuration
+	storage := Named("storage")
+	request := With("request", "r-1")
+	folder := t.TempDir()
+	plain := filepath.Join(folder, "plain.log")
+	rotating := filepath.Join(folder, "rotating.log")
+
+	zero := 0
+	require.NoError(t, Configure(&Config{
+		Level:  "info",
+		Levels: "storage=debug",
+		Format: "json",
+		Flags:  "none",
+		Prefix: "app",
+		Outputs: []OutputConfig{
+			{Type: "file", Path: plain, Format: "text"},
+			{Type: "file", Path: rotating, Level: "error", Rotate: &RotateConfig{
+				MaxSize: "1 MB", MaxTotalSize: "1 GB", MinFreeSpace: "1 KB", Schedule: "daily", Location: "UTC", Backups: &zero, CompressLevel: &zero, ModePerm: "0600",
+			}},
+		},
+	}))
+	require.Equal(t, origin, DefaultLogger())
+	Debug("hidden")
+	storage.Debug("query")
+	request.Info("handled")
+	Error("failed")
+
+	data, err := os.ReadFile(plain)
+	require.NoError(t, err)
+	require.Equal(t, "app[DEBUG] storage: query\napp[INFO ] handled request=r-1\napp[ERROR] failed\n", string(data))
+	data, err = os.ReadFile(rotating)
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(string(data), "\n"))
+	require.Contains(t, string(data), `"msg":"failed","prefix":"app"`)
+	info, err := os.Stat(rotating)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	t.Run("invalid", func(t *testing.T) {
+		current := DefaultLogger()
+		for _, cfg := range []*Config{
+			{Level: "loud"},
+			{Levels: "=debug"},
+			{Format: "xml"},
+			{Flags: "week"},
+			{Outputs: []OutputConfig{{Type: "socket"}}},
+			{Outputs: []OutputConfig{{Type: "file"}}},
+			{Outputs: []OutputConfig{{Type: "stdout", Level: "loud"}}},
+			{Outputs: []OutputConfig{{Type: "file", Path: filepath.Join(folder, "a.log"), Rotate: &RotateConfig{MaxSize: "big"}}}},
+			{Outputs: []OutputConfig{{Type: "file", Path: filepath.Join(folder, "a.log"), Rotate: &RotateConfig{Schedule: "yearly"}}}},
+			{Outputs: []OutputConfig{{Type: "file", Path: filepath.Join(folder, "a.log"), Rotate: &RotateConfig{Location: "Mars/Olympus"}}}},
+			{Outputs: []OutputConfig{{Type: "file", Path: filepath.Join(folder, "a.log"), Rotate: &RotateConfig{BackupTemplate: "{name}"}}}},
+			{Outputs: []OutputConfig{{Type: "file", Path: filepath.Join(folder, "a.log"), Rotate: &RotateConfig{MaxTotalSize: "huge"}}}},
+			{Outputs: []OutputConfig{{Type: "file", Path: filepath.Join(folder, "a.log"), Rotate: &RotateConfig{MinFreeSpace: "-1GB"}}}},
+		} {
+			require.Error(t, Configure(cfg), cfg)
+			require.Equal(t, current, DefaultLogger())
+		}
+	})
+
+	t.Run("reconfigure", func(t *testing.T) {
+		other := filepath.Join(folder, "other.log")
+		require.NoError(t, Configure(&Config{Flags: "none", Outputs: []OutputConfig{{Type: "file", Path: other}}}))
+		Warn("again")
+		Info("hidden")
+		storage.Error("moved")
+		data, err := os.ReadFile(other)
+		require.NoError(t, err)
+		require.Equal(t, "[WARN ] again\n[ERROR] storage: moved\n", string(data))
+		require.Empty(t, Levels())
+		// the previous outputs are closed
+		data, err = os.ReadFile(rotating)
+		require.NoError(t, err)
+		require.Equal(t, 1, strings.Count(string(data), "\n"))
+	})
+
+	t.Run("other logger", func(t *testing.T) {
+		defer SetLogger(origin)
+		SetLogger(struct{ Logger }{origin})
+		require.NoError(t, Configure(&Config{Flags: "none", Outputs: []OutputConfig{{Type: "file", Path: plain}}}))
+		installed, ok := DefaultLogger().(*defaultLogger)
+		require.True(t, ok)
+		require.NotEqual(t, origin, installed)
+	})
+}
@@ -0,0 +1,136 @@
+package log
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// ANSI escape sequences used by the ConsoleEncoder.
+const (
+	colorReset   = "\x1b[0m"
+	colorFaint   = "\x1b[2m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorCyan    = "\x1b[36m"
+	colorGray    = "\x1b[90m"
+	colorBoldRed = "\x1b[1;31m"
+)
+
+// levelColors are the colors of the level tags of TRACE..FATAL.
+var levelColors = []string{
+	colorGray,
+	colorCyan,
+	colorGreen,
+	colorYellow,
+	colorRed,
+	colorBoldRed,
+}
+
+// levelColor returns the color of the level tag, levels registered by RegisterLevel have
+// the color of the closest built-in level below them.
+func levelColor(lv Level) string {
+	if lv >= TRACE && lv <= FATAL {
+		return levelColors[lv]
+	}
+	index := lv.Severity() / severityStep
+	switch {
+	case index < int(TRACE):
+		index = int(TRACE)
+	case index > int(FATAL):
+		index = int(FATAL)
+	}
+	return levelColors[index]
+}
+
+// ConsoleEncoder renders entries like the TextEncoder and colors the level tags with ANSI
+// escape sequences, and optionally the time and caller columns. It is meant for humans
+// reading the logs on a terminal:
+//
+//	log.SetEncoder(log.NewConsoleEncoder(os.Stderr))
+//	log.SetOutput(os.Stderr)
+type ConsoleEncoder struct {
+	// Color enables the ANSI colors, the entries are rendered like the TextEncoder if unset.
+	Color bool
+	// ColorTime renders the time column in faint.
+	ColorTime bool
+	// ColorCaller renders the caller column in blue.
+	ColorCaller bool
+}
+
+// NewConsoleEncoder returns a ConsoleEncoder for the writer, colors are enabled if
+// ColorEnabled(writer) reports true.
+func NewConsoleEncoder(writer io.Writer) *ConsoleEncoder {
+	color := ColorEnabled(writer)
+	return &ConsoleEncoder{Color: color, ColorTime: color, ColorCaller: color}
+}
+
+// Encode implements the Encoder interface.
+func (e *ConsoleEncoder) Encode(buf []byte, entry *Entry) []byte {
+	flag := entry.Flags
+	if flag&Lmsgprefix == 0 {
+		buf = append(buf, entry.Prefix...)
+	}
+	buf = appendColored(buf, e.Color && e.ColorTime, colorFaint, entry, appendTime)
+	buf = appendColored(buf, e.Color && e.ColorCaller, colorBlue, entry, appendCaller)
+	if flag&Lmsgprefix != 0 {
+		buf = append(buf, entry.Prefix...)
+	}
+	tag := entry.Level.String()
+	if e.Color {
+		text := strings.TrimRight(tag, " ")
+		buf = append(buf, levelColor(entry.Level)...)
+		buf = append(buf, text...)
+		buf = append(buf, colorReset...)
+		tag = tag[len(text):]
+	}
+	buf = append(buf, tag...)
+	return appendBody(buf, entry)
+}
+
+// appendColored appends the column rendered by appendColumn, colored if enabled. The
+// trailing separator of the column is not colored.
+func appendColored(buf []byte, enabled bool, color string, entry *Entry, appendColumn func([]byte, *Entry) []byte) []byte {
+	if !enabled {
+		return appendColumn(buf, entry)
+	}
+	start := len(buf)
+	buf = append(buf, color...)
+	buf = appendColumn(buf, entry)
+	if len(buf) == start+len(color) {
+		return buf[:start]
+	}
+	end := len(buf)
+	for end > start+len(color) && (buf[end-1] == ' ' || buf[end-1] == ':') {
+		end--
+	}
+	sep := string(buf[end:])
+	buf = append(buf[:end], colorReset...)
+	return append(buf, sep...)
+}
+
+// ColorEnabled reports whether colors should be used on the writer:
+//   - false if the NO_COLOR environment variable is set and not empty,
+//   - true if the FORCE_COLOR environment variable is set and not empty, "0" or "false",
+//   - otherwise whether the writer is a terminal.
+func ColorEnabled(writer io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if force, ok := os.LookupEnv("FORCE_COLOR"); ok && force != "" {
+		return force != "0" && !strings.EqualFold(force, "false")
+	}
+	return isTerminal(writer)
+}
+
+// isTerminal reports whether the writer is a file connected to a terminal, character
+// devices such as /dev/null are not terminals.
+func isTerminal(writer io.Writer) bool {
+	file, ok := writer.(*os.File)
+	if !ok || file == nil {
+		return false
+	}
+	return isTerminalFd(file.Fd())
+}
@@ -0,0 +1,108 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/stkali/utility/log/internal/callertest"
+)
+
+func TestConsoleEncoder(t *testing.T) {
+	at := time.Date(2009, time.January, 23, 1, 23, 23, 0, time.Local)
+	caller := Caller{File: "/a/b/c/d.go", Line: 23}
+	entry := Entry{Time: at, Level: WARN, Caller: caller, Message: "hello", Fields: []Field{{Key: "a", Value: 1}},
+		Prefix: "app: ", Flags: LstdFlags | Lshortfile}
+
+	t.Run("plain", func(t *testing.T) {
+		encoder := &ConsoleEncoder{ColorTime: true, ColorCaller: true}
+		require.Equal(t, string(NewTextEncoder().Encode(nil, &entry)), string(encoder.Encode(nil, &entry)))
+	})
+
+	t.Run("level only", func(t *testing.T) {
+		encoder := &ConsoleEncoder{Color: true}
+		require.Equal(t, "app: 2009/01/23 01:23:23 d.go:23: \x1b[33m[WARN ]\x1b[0m hello a=1\n",
+			string(encoder.Encode(nil, &entry)))
+	})
+
+	t.Run("all columns", func(t *testing.T) {
+		encoder := &ConsoleEncoder{Color: true, ColorTime: true, ColorCaller: true}
+		require.Equal(t, "app: \x1b[2m2009/01/23 01:23:23\x1b[0m \x1b[34md.go:23\x1b[0m: \x1b[33m[WARN ]\x1b[0m hello a=1\n",
+			string(encoder.Encode(nil, &entry)))
+
+		// columns disabled by the flags are not rendered
+		noFlags := Entry{Level: ERROR, Message: "hello"}
+		require.Equal(t, "\x1b[31m[ERROR]\x1b[0m hello\n", string(encoder.Encode(nil, &noFlags)))
+	})
+
+	t.Run("level colors", func(t *testing.T) {
+		require.Equal(t, colorGray, levelColor(TRACE))
+		require.Equal(t, colorBoldRed, levelColor(FATAL))
+		require.Equal(t, colorGray, levelColor(Level(-3)))
+		require.Equal(t, colorBoldRed, levelColor(Level(100)))
+	})
+}
+
+func TestColorEnabled(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "log"))
+	require.NoError(t, err)
+	defer file.Close()
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	defer null.Close()
+
+	cases := []struct {
+		name   string
+		env    map[string]string
+		writer any
+		want   bool
+	}{
+		{"buffer", nil, new(bytes.Buffer), false},
+		{"regular file", nil, file, false},
+		{"null device", nil, null, false},
+		{"force", map[string]string{"FORCE_COLOR": "1"}, new(bytes.Buffer), true},
+		{"force zero", map[string]string{"FORCE_COLOR": "0"}, new(bytes.Buffer), false},
+		{"force false", map[string]string{"FORCE_COLOR": "false"}, new(bytes.Buffer), false},
+		{"no color wins", map[string]string{"FORCE_COLOR": "1", "NO_COLOR": "1"}, new(bytes.Buffer), false},
+		{"empty no color", map[string]string{"FORCE_COLOR": "1", "NO_COLOR": ""}, new(bytes.Buffer), true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", "")
+			t.Setenv("FORCE_COLOR", "")
+			for key, value := range c.env {
+				t.Setenv(key, value)
+			}
+			switch w := c.writer.(type) {
+			case *bytes.Buffer:
+				require.Equal(t, c.want, ColorEnabled(w))
+				require.Equal(t, c.want, NewConsoleEncoder(w).Color)
+			case *os.File:
+				require.Equal(t, c.want, ColorEnabled(w))
+			}
+		})
+	}
+	require.False(t, isTerminal((*os.File)(nil)))
+}
+
+func TestSetConsoleEncoder(t *testing.T) {
+	recorder := new(bytes.Buffer)
+	SetOutput(recorder)
+	SetFlags(Lshortfile)
+	SetPrefix("")
+	SetEncoder(&ConsoleEncoder{Color: true, ColorCaller: true})
+	defer func() {
+		SetEncoder(nil)
+		SetFlags(defaultFlags)
+		SetOutput(os.Stdout)
+	}()
+	Error("line=", callertest.Line())
+	got := recorder.String()
+	line := strings.TrimSuffix(got[strings.LastIndex(got, "=")+1:], "\n")
+	require.Equal(t, "\x1b[34mconsole_test.go:"+line+"\x1b[0m: \x1b[31m[ERROR]\x1b[0m line="+line+"\n", got)
+}
@@ -0,0 +1,136 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// ContextExtractor returns the fields carried by a context, such as a request ID or
+// trace and span IDs.
+type ContextExtractor func(ctx context.Context) []Field
+
+// fieldsKey is the context key of the fields added by NewContext.
+type fieldsKey struct{}
+
+var (
+	// extractors holds the []ContextExtractor registered by RegisterContextExtractor,
+	// the slice is never modified after it has been stored.
+	extractors atomic.Value
+	// extractorsMtx serializes the writers of extractors.
+	extractorsMtx sync.Mutex
+)
+
+// RegisterContextExtractor registers an extractor whose fields are appended to every line
+// logged through WithContext or the *Context functions. Extractors run in the order of
+// registration, nil is ignored.
+func RegisterContextExtractor(extractor ContextExtractor) {
+	if extractor == nil {
+		return
+	}
+	extractorsMtx.Lock()
+	defer extractorsMtx.Unlock()
+	registered, _ := extractors.Load().([]ContextExtractor)
+	updated := make([]ContextExtractor, 0, len(registered)+1)
+	updated = append(updated, registered...)
+	extractors.Store(append(updated, extractor))
+}
+
+// ContextValue returns an extractor that adds the value stored in the context under key
+// as the field named name, if the value is not nil.
+func ContextValue(key any, name string) ContextExtractor {
+	return func(ctx context.Context) []Field {
+		if value := ctx.Value(key); value != nil {
+			return []Field{{Key: name, Value: value}}
+		}
+		return nil
+	}
+}
+
+// NewContext returns a copy of ctx carrying the given key/value pairs in addition to the
+// pairs carried by ctx, they are appended to every line logged within the returned context.
+func NewContext(ctx context.Context, keysAndValues ...any) context.Context {
+	parent, _ := ctx.Value(fieldsKey{}).([]Field)
+	return context.WithValue(ctx, fieldsKey{}, joinFields(parent, toFields(keysAndValues)))
+}
+
+// ContextFields returns the fields added to ctx by NewContext followed by the fields of the
+// registered extractors.
+func ContextFields(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).([]Field)
+	registered, _ := extractors.Load().([]ContextExtractor)
+	for _, extractor := range registered {
+		fields = joinFields(fields, extractor(ctx))
+	}
+	return fields
+}
+
+// WithContext returns a child of the default logger carrying the fields of ctx,
+// see ContextFields.
+func WithContext(ctx context.Context) Logger {
+	return loadLogger().WithContext(ctx)
+}
+
+// FatalContext cads the Fatal method of the default logger with the fields of ctx and then
+// exits, see SetFatalPolicy.
+func FatalContext(ctx context.Context, args ...any) {
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.withContext(ctx).logf(FATAL, nil, args...)
+		return
+	}
+	l.WithContext(ctx).Fatal(copyArgs(args)...)
+}
+
+// ErrorContext cads the Error method of the default logger with the fields of ctx.
+func ErrorContext(ctx context.Context, args ...any) {
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.withContext(ctx).logf(ERROR, nil, args...)
+		return
+	}
+	l.WithContext(ctx).Error(copyArgs(args)...)
+}
+
+// WarnContext cads the Warn method of the default logger with the fields of ctx.
+func WarnContext(ctx context.Context, args ...any) {
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.withContext(ctx).logf(WARN, nil, args...)
+		return
+	}
+	l.WithContext(ctx).Warn(copyArgs(args)...)
+}
+
+// InfoContext cads the Info method of the default logger with the fields of ctx.
+func InfoContext(ctx context.Context, args ...any) {
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.withContext(ctx).logf(INFO, nil, args...)
+		return
+	}
+	l.WithContext(ctx).Info(copyArgs(args)...)
+}
+
+// DebugContext cads the Debug method of the default logger with the fields of ctx.
+func DebugContext(ctx context.Context, args ...any) {
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.withContext(ctx).logf(DEBUG, nil, args...)
+		return
+	}
+	l.WithContext(ctx).Debug(copyArgs(args)...)
+}
+
+// TraceContext cads the Trace method of the default logger with the fields of ctx.
+func TraceContext(ctx context.Context, args ...any) {
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.withContext(ctx).logf(TRACE, nil, args...)
+		return
+	}
+	l.WithContext(ctx).Trace(copyArgs(args)...)
+}
@@ -0,0 +1,62 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type requestIDKey struct{}
+
+func TestContextFields(t *testing.T) {
+	defer func() { extractors = atomic.Value{} }()
+
+	ctx := context.Background()
+	require.Nil(t, ContextFields(ctx))
+	var nilCtx context.Context
+	require.Nil(t, ContextFields(nilCtx))
+
+	ctx = NewContext(ctx, "user", "alice")
+	child := NewContext(ctx, "step", 1)
+	require.Equal(t, []Field{{Key: "user", Value: "alice"}}, ContextFields(ctx))
+	require.Equal(t, []Field{{Key: "user", Value: "alice"}, {Key: "step", Value: 1}}, ContextFields(child))
+
+	RegisterContextExtractor(nil)
+	RegisterContextExtractor(ContextValue(requestIDKey{}, "request"))
+	RegisterContextExtractor(func(ctx context.Context) []Field {
+		return []Field{{Key: "trace", Value: "t-1"}}
+	})
+	require.Equal(t, []Field{{Key: "user", Value: "alice"}, {Key: "trace", Value: "t-1"}}, ContextFields(ctx))
+	ctx = context.WithValue(ctx, requestIDKey{}, "r-1")
+	require.Equal(t, []Field{{Key: "user", Value: "alice"}, {Key: "request", Value: "r-1"}, {Key: "trace", Value: "t-1"}}, ContextFields(ctx))
+}
+
+func TestWithContext(t *testing.T) {
+	defer func() { extractors = atomic.Value{} }()
+	recorder := new(bytes.Buffer)
+	SetOutput(recorder)
+	SetLevel(TRACE)
+	SetFlags(0)
+	SetPrefix("")
+
+	// without fields the logger is returned as is
+	require.Equal(t, DefaultLogger(), WithContext(context.Background()))
+
+	RegisterContextExtractor(ContextValue(requestIDKey{}, "request"))
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "r-1")
+	ctx = NewContext(ctx, "user", "alice")
+
+	WithContext(ctx).With("k", "v").Infow("login", "ok", true)
+	require.Equal(t, INFO.String()+"login user=alice request=r-1 k=v ok=true\n", recorder.String())
+
+	for lv, fn := range []func(context.Context, ...any){
+		TraceContext, DebugContext, InfoContext, WarnContext, ErrorContext, FatalContext,
+	} {
+		recorder.Reset()
+		fn(ctx, "msg")
+		require.Equal(t, Level(lv).String()+"msg user=alice request=r-1\n", recorder.String())
+	}
+}
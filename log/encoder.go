@@ -0,0 +1,418 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Caller describes the source location that emitted a log entry.
+// The zero value means the caller is unknown.
+type Caller struct {
+	File     string
+	Line     int
+	Function string
+}
+
+// Defined reports whether the caller has been resolved.
+func (c Caller) Defined() bool {
+	return c.File != ""
+}
+
+// shortFile returns the final element of the file name.
+func (c Caller) shortFile() string {
+	for i := len(c.File) - 1; i > 0; i-- {
+		if c.File[i] == '/' {
+			return c.File[i+1:]
+		}
+	}
+	return c.File
+}
+
+// shortFunction returns the function name without the import path of its package.
+func (c Caller) shortFunction() string {
+	for i := len(c.Function) - 1; i > 0; i-- {
+		if c.Function[i] == '/' {
+			return c.Function[i+1:]
+		}
+	}
+	return c.Function
+}
+
+// Entry is a single log record, it is built by the logger and rendered by an Encoder.
+type Entry struct {
+	Time  time.Time
+	Level Level
+	// Name is the name of the logger given by Named.
+	Name    string
+	Caller  Caller
+	Message string
+	Fields  []Field
+	// Stack is the rendered stack trace of the logged error, see Traceback.
+	Stack string
+	// Causes is the tree of the errors wrapped by the logged error, one error per line
+	// indented by its depth, see Traceback.
+	Causes []string
+	// Prefix and Flags are the settings of the logger when the entry was created.
+	Prefix string
+	Flags  int
+}
+
+// Encoder renders entries into bytes.
+type Encoder interface {
+	// Encode appends the rendered entry, terminated by a newline, to buf and returns
+	// the extended buffer.
+	Encode(buf []byte, entry *Entry) []byte
+}
+
+// TextEncoder renders entries in the format of the standard log package, followed by the
+// level tag, the logger name, the message and the fields, and the causes and stack trace
+// of the logged error on the next lines:
+//
+//	prefix: 2009/01/23 01:23:23 d.go:23: [ERROR] name: message key=value
+//	Causes:
+//	    open a.txt: no such file or directory
+//	        no such file or directory
+//	Traceback:
+//	    main.main(...)
+//	         /a/b/c/d.go:23
+//
+// It is the default encoder.
+type TextEncoder struct{}
+
+// NewTextEncoder returns a new TextEncoder.
+func NewTextEncoder() *TextEncoder {
+	return &TextEncoder{}
+}
+
+// Encode implements the Encoder interface.
+func (e *TextEncoder) Encode(buf []byte, entry *Entry) []byte {
+	buf = appendHeader(buf, entry)
+	buf = append(buf, entry.Level.String()...)
+	return appendBody(buf, entry)
+}
+
+// appendBody appends the logger name, the message, the fields, the newline and the
+// traceback of the entry. A trailing newline of the message is replaced by the newline
+// after the fields.
+func appendBody(buf []byte, entry *Entry) []byte {
+	if entry.Name != "" {
+		buf = append(buf, entry.Name...)
+		buf = append(buf, ": "...)
+	}
+	buf = append(buf, strings.TrimSuffix(entry.Message, "\n")...)
+	buf = appendFields(buf, entry.Fields)
+	buf = append(buf, '\n')
+	if len(entry.Causes) > 0 {
+		buf = append(buf, "Causes:\n"...)
+		for _, cause := range entry.Causes {
+			buf = append(buf, "    "...)
+			buf = append(buf, cause...)
+			buf = append(buf, '\n')
+		}
+	}
+	return append(buf, entry.Stack...)
+}
+
+// appendHeader appends the header of the standard log package to buf:
+//   - prefix (if it's not blank and Lmsgprefix is unset),
+//   - date and/or time (if corresponding flags are provided),
+//   - file and line number and/or function name (if corresponding flags are provided),
+//   - prefix (if it's not blank and Lmsgprefix is set).
+func appendHeader(buf []byte, entry *Entry) []byte {
+	flag := entry.Flags
+	if flag&Lmsgprefix == 0 {
+		buf = append(buf, entry.Prefix...)
+	}
+	buf = appendTime(buf, entry)
+	buf = appendCaller(buf, entry)
+	if flag&Lmsgprefix != 0 {
+		buf = append(buf, entry.Prefix...)
+	}
+	return buf
+}
+
+// appendTime appends the date and/or time of the entry followed by a space according to
+// the flags of the entry.
+func appendTime(buf []byte, entry *Entry) []byte {
+	flag := entry.Flags
+	if flag&(Ldate|Ltime|Lmicroseconds) == 0 {
+		return buf
+	}
+	t := entry.Time
+	if flag&LUTC != 0 {
+		t = t.UTC()
+	}
+	if flag&Ldate != 0 {
+		year, month, day := t.Date()
+		buf = appendInt(buf, year, 4)
+		buf = append(buf, '/')
+		buf = appendInt(buf, int(month), 2)
+		buf = append(buf, '/')
+		buf = appendInt(buf, day, 2)
+		buf = append(buf, ' ')
+	}
+	if flag&(Ltime|Lmicroseconds) != 0 {
+		hour, min, sec := t.Clock()
+		buf = appendInt(buf, hour, 2)
+		buf = append(buf, ':')
+		buf = appendInt(buf, min, 2)
+		buf = append(buf, ':')
+		buf = appendInt(buf, sec, 2)
+		if flag&Lmicroseconds != 0 {
+			buf = append(buf, '.')
+			buf = appendInt(buf, t.Nanosecond()/1e3, 6)
+		}
+		buf = append(buf, ' ')
+	}
+	return buf
+}
+
+// appendCaller appends the file and line number of the entry followed by ": " according to
+// the flags of the entry.
+func appendCaller(buf []byte, entry *Entry) []byte {
+	flag := entry.Flags
+	if flag&(Lshortfile|Llongfile|Lfuncname) == 0 {
+		return buf
+	}
+	if flag&(Lshortfile|Llongfile) != 0 {
+		file, line := "???", 0
+		if entry.Caller.Defined() {
+			file, line = entry.Caller.File, entry.Caller.Line
+			if flag&Lshortfile != 0 {
+				file = entry.Caller.shortFile()
+			}
+		}
+		buf = append(buf, file...)
+		buf = append(buf, ':')
+		buf = appendInt(buf, line, -1)
+		if flag&Lfuncname != 0 {
+			buf = append(buf, ' ')
+		}
+	}
+	if flag&Lfuncname != 0 {
+		buf = append(buf, callerFunction(entry)...)
+	}
+	return append(buf, ": "...)
+}
+
+// callerFunction returns the function of the caller of the entry, without the import path
+// unless Llongfile is set without Lshortfile.
+func callerFunction(entry *Entry) string {
+	if entry.Caller.Function == "" {
+		return "???"
+	}
+	if entry.Flags&(Lshortfile|Llongfile) == Llongfile {
+		return entry.Caller.Function
+	}
+	return entry.Caller.shortFunction()
+}
+
+// appendInt appends the decimal i to buf, zero-padded to wid digits (wid < 0 means no padding).
+func appendInt(buf []byte, i int, wid int) []byte {
+	// assemble decimal in reverse order
+	var b [20]byte
+	bp := len(b) - 1
+	for i >= 10 || wid > 1 {
+		wid--
+		q := i / 10
+		b[bp] = byte('0' + i - q*10)
+		bp--
+		i = q
+	}
+	// i < 10
+	b[bp] = byte('0' + i)
+	return append(buf, b[bp:]...)
+}
+
+// jsonReservedKey reports whether the key is one of the keys rendered by JSONEncoder
+// outside the fields of the entry.
+func jsonReservedKey(key string) bool {
+	switch key {
+	case "time", "level", "logger", "caller", "function", "msg", "prefix", "causes", "stack":
+		return true
+	}
+	return false
+}
+
+// JSONEncoder renders every entry as one JSON object per line with the keys `time`, `level`,
+// optionally `logger`, `caller`, `msg`, optionally `prefix`, followed by the fields of the entry
+// and optionally `causes` (array) and `stack` of the logged error. A field whose key is one
+// of these keys, or `function`, is rendered with the key prefixed by `fields.`, e.g.
+// `fields.level`, so that the object never holds a duplicate key.
+//
+// The LUTC flag switches the time to UTC, and Llongfile without Lshortfile renders the full
+// file name of the caller, other flags are ignored.
+type JSONEncoder struct {
+	// TimeLayout(default: time.RFC3339Nano) is the layout of the `time` key.
+	TimeLayout string
+}
+
+// NewJSONEncoder returns a new JSONEncoder.
+func NewJSONEncoder() *JSONEncoder {
+	return &JSONEncoder{TimeLayout: time.RFC3339Nano}
+}
+
+// Encode implements the Encoder interface.
+func (e *JSONEncoder) Encode(buf []byte, entry *Entry) []byte {
+	layout := e.TimeLayout
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	t := entry.Time
+	if entry.Flags&LUTC != 0 {
+		t = t.UTC()
+	}
+	buf = append(buf, `{"time":"`...)
+	buf = t.AppendFormat(buf, layout)
+	buf = append(buf, `","level":`...)
+	buf = appendJSONString(buf, entry.Level.Name())
+	if entry.Name != "" {
+		buf = append(buf, `,"logger":`...)
+		buf = appendJSONString(buf, entry.Name)
+	}
+	if entry.Caller.Defined() {
+		file := entry.Caller.File
+		if entry.Flags&(Lshortfile|Llongfile) != Llongfile {
+			file = entry.Caller.shortFile()
+		}
+		buf = append(buf, `,"caller":"`...)
+		buf = appendJSONEscaped(buf, file)
+		buf = append(buf, ':')
+		buf = strconv.AppendInt(buf, int64(entry.Caller.Line), 10)
+		buf = append(buf, '"')
+		if entry.Flags&Lfuncname != 0 && entry.Caller.Function != "" {
+			buf = append(buf, `,"function":`...)
+			buf = appendJSONString(buf, callerFunction(entry))
+		}
+	}
+	buf = append(buf, `,"msg":`...)
+	buf = appendJSONString(buf, entry.Message)
+	if entry.Prefix != "" {
+		buf = append(buf, `,"prefix":`...)
+		buf = appendJSONString(buf, entry.Prefix)
+	}
+	for index := range entry.Fields {
+		buf = append(buf, ',')
+		key := entry.Fields[index].Key
+		if jsonReservedKey(key) {
+			buf = append(buf, `"fields.`...)
+			buf = appendJSONEscaped(buf, key)
+			buf = append(buf, '"')
+		} else {
+			buf = appendJSONString(buf, key)
+		}
+		buf = append(buf, ':')
+		buf = appendJSONField(buf, &entry.Fields[index])
+	}
+	if len(entry.Causes) > 0 {
+		buf = append(buf, `,"causes":[`...)
+		for index, cause := range entry.Causes {
+			if index > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendJSONString(buf, cause)
+		}
+		buf = append(buf, ']')
+	}
+	if entry.Stack != "" {
+		buf = append(buf, `,"stack":`...)
+		buf = appendJSONString(buf, entry.Stack)
+	}
+	return append(buf, '}', '\n')
+}
+
+// appendJSONField appends the JSON representation of the value of the field to buf.
+func appendJSONField(buf []byte, f *Field) []byte {
+	switch f.valueKind() {
+	case intKind, int64Kind:
+		return strconv.AppendInt(buf, f.num, 10)
+	case boolKind:
+		return strconv.AppendBool(buf, f.num != 0)
+	case durationKind:
+		return appendJSONString(buf, time.Duration(f.num).String())
+	case stringKind:
+		return appendJSONString(buf, f.str)
+	default:
+		return appendJSONValue(buf, f.Value)
+	}
+}
+
+// appendJSONValue appends the JSON representation of v to buf.
+// Errors and fmt.Stringer values are rendered as strings, values that cannot be marshaled
+// are rendered with fmt.Sprint.
+func appendJSONValue(buf []byte, v any) []byte {
+	switch value := v.(type) {
+	case nil:
+		return append(buf, "null"...)
+	case string:
+		return appendJSONString(buf, value)
+	case bool:
+		return strconv.AppendBool(buf, value)
+	case int:
+		return strconv.AppendInt(buf, int64(value), 10)
+	case int64:
+		return strconv.AppendInt(buf, value, 10)
+	case error:
+		return appendJSONString(buf, value.Error())
+	case json.Marshaler:
+	case fmt.Stringer:
+		return appendJSONString(buf, value.String())
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return appendJSONString(buf, fmt.Sprint(v))
+	}
+	return append(buf, data...)
+}
+
+// appendJSONString appends s as a quoted JSON string to buf.
+func appendJSONString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	buf = appendJSONEscaped(buf, s)
+	return append(buf, '"')
+}
+
+const hex = "0123456789abcdef"
+
+// appendJSONEscaped appends s to buf, escaping the characters not allowed in JSON strings.
+func appendJSONEscaped(buf []byte, s string) []byte {
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				buf = append(buf, '\\', c)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, `�`...)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	return append(buf, s[start:]...)
+}
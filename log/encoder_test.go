@@ -0,0 +1,171 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextEncoder(t *testing.T) {
+	at := time.Date(2009, time.January, 23, 1, 23, 23, 123123000, time.Local)
+	caller := Caller{File: "/a/b/c/d.go", Line: 23, Function: "example.com/a/b.(*Server).handle"}
+	cases := []struct {
+		name  string
+		entry Entry
+		want  string
+	}{
+		{"no flags", Entry{Level: INFO, Message: "hello"}, "[INFO ] hello\n"},
+		{"prefix", Entry{Level: INFO, Message: "hello", Prefix: "app: "}, "app: [INFO ] hello\n"},
+		{"msg prefix", Entry{Time: at, Level: INFO, Message: "hello", Prefix: "app: ", Flags: Ldate | Lmsgprefix},
+			"2009/01/23 app: [INFO ] hello\n"},
+		{"std flags", Entry{Time: at, Level: WARN, Message: "hello", Flags: LstdFlags}, "2009/01/23 01:23:23 [WARN ] hello\n"},
+		{"microseconds", Entry{Time: at, Level: WARN, Message: "hello", Flags: Lmicroseconds},
+			"01:23:23.123123 [WARN ] hello\n"},
+		{"long file", Entry{Level: ERROR, Message: "hello", Caller: caller, Flags: Llongfile},
+			"/a/b/c/d.go:23: [ERROR] hello\n"},
+		{"short file", Entry{Level: ERROR, Message: "hello", Caller: caller, Flags: Llongfile | Lshortfile},
+			"d.go:23: [ERROR] hello\n"},
+		{"unknown caller", Entry{Level: ERROR, Message: "hello", Flags: Lshortfile}, "???:0: [ERROR] hello\n"},
+		{"function", Entry{Level: ERROR, Message: "hello", Caller: caller, Flags: Lshortfile | Lfuncname},
+			"d.go:23 b.(*Server).handle: [ERROR] hello\n"},
+		{"long function", Entry{Level: ERROR, Message: "hello", Caller: caller, Flags: Llongfile | Lfuncname},
+			"/a/b/c/d.go:23 example.com/a/b.(*Server).handle: [ERROR] hello\n"},
+		{"function only", Entry{Level: ERROR, Message: "hello", Caller: caller, Flags: Lfuncname},
+			"b.(*Server).handle: [ERROR] hello\n"},
+		{"unknown function", Entry{Level: ERROR, Message: "hello", Flags: Lfuncname}, "???: [ERROR] hello\n"},
+		{"name", Entry{Level: INFO, Name: "storage.db", Message: "hello"}, "[INFO ] storage.db: hello\n"},
+		{"fields", Entry{Level: DEBUG, Message: "hello", Fields: []Field{{Key: "a", Value: 1}, {Key: "b", Value: "x y"}}},
+			`[DEBUG] hello a=1 b="x y"` + "\n"},
+		{"trailing newline", Entry{Level: INFO, Message: "hello\n"}, "[INFO ] hello\n"},
+		{"trailing newline and fields", Entry{Level: INFO, Message: "hello\n", Fields: []Field{Str("my key", "v")}},
+			`[INFO ] hello "my key"=v` + "\n"},
+	}
+	encoder := NewTextEncoder()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.want, string(encoder.Encode(nil, &c.entry)))
+		})
+	}
+
+	t.Run("utc", func(t *testing.T) {
+		local := time.FixedZone("UTC+8", 8*3600)
+		entry := Entry{Time: time.Date(2009, time.January, 23, 1, 0, 0, 0, local), Level: INFO, Flags: LstdFlags | LUTC}
+		require.Equal(t, "2009/01/22 17:00:00 [INFO ] \n", string(encoder.Encode(nil, &entry)))
+	})
+}
+
+type stringer struct{}
+
+func (stringer) String() string { return "stringer" }
+
+func TestJSONEncoder(t *testing.T) {
+	at := time.Date(2009, time.January, 23, 1, 23, 23, 0, time.UTC)
+	entry := Entry{
+		Time:    at,
+		Level:   WARN,
+		Caller:  Caller{File: "/a/b/c/d.go", Line: 23},
+		Message: "hello \"json\"\n",
+		Prefix:  "app",
+		Fields: []Field{
+			{Key: "int", Value: 1},
+			{Key: "float", Value: 1.5},
+			{Key: "bool", Value: true},
+			{Key: "nil", Value: nil},
+			{Key: "err", Value: errors.New("boom")},
+			{Key: "stringer", Value: stringer{}},
+			{Key: "slice", Value: []int{1, 2}},
+			{Key: "invalid", Value: math.Inf(1)},
+			{Key: "at", Value: at},
+			Int("typed_int", 7),
+			Str("typed_str", "a\"b"),
+			Dur("typed_dur", time.Second),
+			Bool("typed_bool", true),
+		},
+	}
+	encoder := NewJSONEncoder()
+	data := encoder.Encode(nil, &entry)
+	require.True(t, bytes.HasSuffix(data, []byte("}\n")))
+	require.Equal(t, 1, bytes.Count(data, []byte("\n")))
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.Equal(t, map[string]any{
+		"time":       "2009-01-23T01:23:23Z",
+		"level":      "WARN",
+		"caller":     "d.go:23",
+		"msg":        "hello \"json\"\n",
+		"prefix":     "app",
+		"int":        float64(1),
+		"float":      1.5,
+		"bool":       true,
+		"nil":        nil,
+		"err":        "boom",
+		"stringer":   "stringer",
+		"slice":      []any{float64(1), float64(2)},
+		"invalid":    "+Inf",
+		"at":         "2009-01-23T01:23:23Z",
+		"typed_int":  float64(7),
+		"typed_str":  "a\"b",
+		"typed_dur":  "1s",
+		"typed_bool": true,
+	}, got)
+
+	// long file and custom layout
+	entry = Entry{Time: at, Level: INFO, Name: "storage", Caller: Caller{File: "/a/d.go", Line: 1, Function: "main.main"}, Flags: Llongfile}
+	encoder.TimeLayout = "2006-01-02"
+	require.NoError(t, json.Unmarshal(encoder.Encode(nil, &entry), &got))
+	require.Equal(t, "/a/d.go:1", got["caller"])
+	require.NotContains(t, got, "function")
+	require.Equal(t, "2009-01-23", got["time"])
+	require.Equal(t, "storage", got["logger"])
+	entry.Flags |= Lfuncname
+	got = nil
+	require.NoError(t, json.Unmarshal(encoder.Encode(nil, &entry), &got))
+	require.Equal(t, "main.main", got["function"])
+
+	// the fields never duplicate the keys of the entry
+	entry = Entry{Time: at, Level: INFO, Message: "m", Fields: []Field{Str("level", "high"), Str("msg", "x"), Int("time", 1), Str("stack", "s")}}
+	data = encoder.Encode(nil, &entry)
+	require.Equal(t, `{"time":"2009-01-23","level":"INFO","msg":"m","fields.level":"high","fields.msg":"x","fields.time":1,"fields.stack":"s"}`+"\n", string(data))
+
+	// invalid utf8 and control characters
+	require.Equal(t, `"a\u0001�\t"`, string(appendJSONString(nil, "a\x01\xff\t")))
+}
+
+func TestSetEncoder(t *testing.T) {
+	recorder := new(bytes.Buffer)
+	SetOutput(recorder)
+	SetLevel(INFO)
+	SetFlags(Lshortfile)
+	SetPrefix("")
+	defer SetEncoder(nil)
+
+	Info("text")
+	require.True(t, strings.HasPrefix(recorder.String(), "encoder_test.go:"))
+	require.True(t, strings.HasSuffix(recorder.String(), ": [INFO ] text\n"))
+
+	recorder.Reset()
+	SetEncoder(NewJSONEncoder())
+	Infow("json", "n", 1)
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(recorder.Bytes(), &got))
+	require.Equal(t, "INFO", got["level"])
+	require.Equal(t, "json", got["msg"])
+	require.Equal(t, float64(1), got["n"])
+	require.True(t, strings.HasPrefix(got["caller"].(string), "encoder_test.go:"))
+
+	// children share the encoder of their parent
+	recorder.Reset()
+	child := With("k", "v")
+	child.Info("child")
+	got = nil
+	require.NoError(t, json.Unmarshal(recorder.Bytes(), &got))
+	require.Equal(t, "child", got["msg"])
+	require.Equal(t, "v", got["k"])
+}
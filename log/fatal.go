@@ -0,0 +1,171 @@
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/stkali/utility/errors"
+)
+
+// defaultShutdownTimeout is the time spent in the shutdown callbacks when
+// FatalPolicy.Timeout <= 0.
+const defaultShutdownTimeout = 5 * time.Second
+
+// FatalPolicy configures what happens after a FATAL entry has been written.
+type FatalPolicy struct {
+	// Code(default: 1) is the exit code of the program.
+	Code int
+	// Panic panics with a *FatalPanic instead of exiting the program, it allows tests to
+	// recover from FATAL.
+	Panic bool
+	// Timeout(default: 5s) bounds the time spent in the callbacks registered by OnFatal,
+	// the program exits even if they have not returned.
+	Timeout time.Duration
+}
+
+// FatalPanic is the value of the panic raised by FATAL when FatalPolicy.Panic is set.
+type FatalPanic struct {
+	Code    int
+	Message string
+}
+
+// Error implements the error interface.
+func (p *FatalPanic) Error() string {
+	return fmt.Sprintf("fatal(exit code %d): %s", p.Code, p.Message)
+}
+
+// shutdownCallback is a callback registered by OnFatal.
+type shutdownCallback struct {
+	fn func() error
+}
+
+var (
+	// fatalMtx protects fatalPolicy, shutdownCallbacks and shutdownDone.
+	fatalMtx          sync.Mutex
+	fatalPolicy       FatalPolicy
+	shutdownCallbacks []*shutdownCallback
+	// shutdownDone is closed when the running shutdown is done, nil if none is running. The
+	// other FATAL entries wait for it so that the program does not exit while the callbacks
+	// run, except the ones logged by a callback which exit at once.
+	shutdownDone chan struct{}
+	// fatalMessage is the message of the last FATAL entry passed to Exit.
+	fatalMessage atomic.Value
+	// runCallbacksName is the name of runCallbacks in the stack traces.
+	runCallbacksName = runtime.FuncForPC(reflect.ValueOf(runCallbacks).Pointer()).Name()
+)
+
+// SetFatalPolicy sets what happens after a FATAL entry has been written.
+func SetFatalPolicy(policy FatalPolicy) {
+	fatalMtx.Lock()
+	defer fatalMtx.Unlock()
+	fatalPolicy = policy
+}
+
+// OnFatal registers fn to be called before FATAL exits the program, e.g. to close files or
+// flush buffered writers. Callbacks are called in the order of registration, errors are
+// reported as warnings. The returned function removes the callback.
+func OnFatal(fn func() error) (remove func()) {
+	callback := &shutdownCallback{fn: fn}
+	fatalMtx.Lock()
+	defer fatalMtx.Unlock()
+	shutdownCallbacks = append(shutdownCallbacks[:len(shutdownCallbacks):len(shutdownCallbacks)], callback)
+	return func() {
+		fatalMtx.Lock()
+		defer fatalMtx.Unlock()
+		callbacks := make([]*shutdownCallback, 0, len(shutdownCallbacks))
+		for _, c := range shutdownCallbacks {
+			if c != callback {
+				callbacks = append(callbacks, c)
+			}
+		}
+		shutdownCallbacks = callbacks
+	}
+}
+
+// FatalExit runs the callbacks registered by OnFatal, then exits the program through Exit
+// (errors.Exit by default, which calls the hook set by errors.SetExitHook) or panics
+// according to the FatalPolicy. It is called by the loggers after writing a FATAL entry
+// with the message of the entry. A FATAL logged while the callbacks run waits for them, it
+// exits at once if it is logged by a callback.
+func FatalExit(msg string) {
+	fatalMtx.Lock()
+	policy, callbacks, done := fatalPolicy, shutdownCallbacks, shutdownDone
+	first := done == nil
+	if first {
+		done = make(chan struct{})
+		shutdownDone = done
+	}
+	fatalMtx.Unlock()
+	if first {
+		shutdown(callbacks, policy.Timeout)
+		fatalMtx.Lock()
+		shutdownDone = nil
+		fatalMtx.Unlock()
+		close(done)
+	} else if !inFatalCallback() {
+		<-done
+	}
+	code := policy.Code
+	if code == 0 {
+		code = 1
+	}
+	if policy.Panic {
+		panic(&FatalPanic{Code: code, Message: msg})
+	}
+	fatalMessage.Store(msg)
+	Exit(code)
+}
+
+// exitFatal is the default Exit, it passes the message of the FATAL entry to the exit hook.
+func exitFatal(code int) {
+	msg, _ := fatalMessage.Load().(string)
+	errors.ExitMessage(code, msg)
+}
+
+// inFatalCallback reports whether the caller is run by a callback registered by OnFatal.
+func inFatalCallback() bool {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if frame.Function == runCallbacksName {
+			return true
+		}
+		if !more {
+			return false
+		}
+	}
+}
+
+// shutdown calls the callbacks in order and waits for them at most timeout.
+func shutdown(callbacks []*shutdownCallback, timeout time.Duration) {
+	if len(callbacks) == 0 {
+		return
+	}
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	done := make(chan struct{})
+	go runCallbacks(callbacks, done)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		errors.Warningf("fatal callbacks did not return in %s", timeout)
+	}
+}
+
+// runCallbacks calls the callbacks in order, then closes done.
+func runCallbacks(callbacks []*shutdownCallback, done chan<- struct{}) {
+	defer close(done)
+	for _, callback := range callbacks {
+		if err := callback.fn(); err != nil {
+			errors.Warningf("failed to run fatal callback, err: %s", err)
+		}
+	}
+}
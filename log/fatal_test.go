@@ -0,0 +1,163 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stkali/utility/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFatalExit(t *testing.T) {
+	recorder := new(bytes.Buffer)
+	SetOutput(recorder)
+	SetFlags(0)
+	SetPrefix("")
+	defer SetOutput(os.Stdout)
+	defer SetFatalPolicy(FatalPolicy{})
+
+	var codes []int
+	defer func(exit func(int)) { Exit = exit }(Exit)
+	Exit = func(code int) {
+		codes = append(codes, code)
+	}
+
+	t.Run("callbacks in order", func(t *testing.T) {
+		var calls []string
+		removeFirst := OnFatal(func() error {
+			calls = append(calls, "first:"+recorder.String())
+			return nil
+		})
+		removeSecond := OnFatal(func() error {
+			calls = append(calls, "second")
+			return errors.Error("close failed")
+		})
+		warning := new(bytes.Buffer)
+		errors.SetWarningOutput(warning)
+		defer errors.SetWarningOutput(os.Stderr)
+
+		codes = nil
+		SetFatalPolicy(FatalPolicy{Code: 3})
+		Fatal("bye")
+		require.Equal(t, []string{"first:" + FATAL.String() + "bye\n", "second"}, calls)
+		require.Equal(t, []int{3}, codes)
+		require.Contains(t, warning.String(), "close failed")
+
+		removeFirst()
+		removeSecond()
+		calls, codes = nil, nil
+		SetFatalPolicy(FatalPolicy{})
+		Fatalf("bye %d", 2)
+		require.Nil(t, calls)
+		require.Equal(t, []int{1}, codes)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		block := make(chan struct{})
+		defer close(block)
+		defer OnFatal(func() error {
+			<-block
+			return nil
+		})()
+		warning := new(bytes.Buffer)
+		errors.SetWarningOutput(warning)
+		defer errors.SetWarningOutput(os.Stderr)
+
+		codes = nil
+		SetFatalPolicy(FatalPolicy{Timeout: 20 * time.Millisecond})
+		start := time.Now()
+		Fatal("stuck")
+		require.Equal(t, []int{1}, codes)
+		require.True(t, time.Since(start) < time.Second)
+		require.Contains(t, warning.String(), "did not return in 20ms")
+	})
+
+	t.Run("fatal in callback", func(t *testing.T) {
+		calls := 0
+		defer OnFatal(func() error {
+			calls++
+			Fatal("again")
+			return nil
+		})()
+		codes = nil
+		Fatal("once")
+		require.Equal(t, 1, calls)
+		require.Equal(t, []int{1, 1}, codes)
+	})
+
+	t.Run("concurrent fatal", func(t *testing.T) {
+		var mtx sync.Mutex
+		defer func(exit func(int)) { Exit = exit }(Exit)
+		Exit = func(code int) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			codes = append(codes, code)
+		}
+		started, release := make(chan struct{}), make(chan struct{})
+		defer OnFatal(func() error {
+			close(started)
+			<-release
+			return nil
+		})()
+		codes = nil
+		SetFatalPolicy(FatalPolicy{})
+		first, second := make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(first)
+			Fatal("first")
+		}()
+		<-started
+		go func() {
+			defer close(second)
+			Fatal("second")
+		}()
+		// the second FATAL waits for the callbacks run by the first one
+		select {
+		case <-second:
+			t.Fatal("the second FATAL exited while the callbacks were running")
+		case <-time.After(50 * time.Millisecond):
+		}
+		close(release)
+		<-first
+		<-second
+		require.Equal(t, []int{1, 1}, codes)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		codes = nil
+		SetFatalPolicy(FatalPolicy{Code: 2, Panic: true})
+		defer func() {
+			p, ok := recover().(*FatalPanic)
+			require.True(t, ok)
+			require.Equal(t, &FatalPanic{Code: 2, Message: "panic"}, p)
+			require.Equal(t, "fatal(exit code 2): panic", p.Error())
+			require.Nil(t, codes)
+		}()
+		With("k", "v").Fatal("panic")
+	})
+}
+
+func TestFatalExitHook(t *testing.T) {
+	SetOutput(new(bytes.Buffer))
+	defer SetOutput(os.Stdout)
+	defer func(exit func(int)) { Exit = exit }(Exit)
+	Exit = exitFatal
+
+	var exitCode, hookCode int
+	var hookMessage string
+	defer errors.ReplaceExit(func(code int) { exitCode = code })()
+	errors.SetExitHook(func(code int, msg string, tracer errors.Tracer) {
+		hookCode, hookMessage = code, msg
+	})
+	defer errors.SetExitHook(nil)
+
+	SetFatalPolicy(FatalPolicy{Code: 4})
+	defer SetFatalPolicy(FatalPolicy{})
+	Fatal("exit")
+	require.Equal(t, 4, exitCode)
+	require.Equal(t, 4, hookCode)
+	require.Equal(t, "exit", hookMessage)
+}
@@ -0,0 +1,216 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// badKey is the key used for values that are not preceded by a string key.
+	badKey = "!BADKEY"
+	// missingValue is the value used when the last key has no value.
+	missingValue = "(MISSING)"
+)
+
+// fieldKind is the type of the value held by a Field without boxing it.
+type fieldKind uint8
+
+const (
+	// anyKind means the value is Field.Value.
+	anyKind fieldKind = iota
+	intKind
+	int64Kind
+	stringKind
+	durationKind
+	boolKind
+)
+
+// Field is a key/value pair attached to a structured log entry.
+//
+// The fields created by the typed constructors Int, Int64, Str, Dur and Bool hold their
+// value without boxing it into an interface, so that they do not allocate, and their Value
+// is nil: use Any to read the value of any field. Setting Value, e.g. by a Hook redacting
+// it, replaces the value of the typed constructors.
+//
+// Field has unexported fields, so it must be built with keyed literals such as
+// Field{Key: "k", Value: v}.
+type Field struct {
+	Key   string
+	Value any
+	// kind, num and str hold the value of the typed constructors.
+	kind fieldKind
+	num  int64
+	str  string
+}
+
+// Int returns a Field holding an int without allocation.
+func Int(key string, value int) Field {
+	return Field{Key: key, kind: intKind, num: int64(value)}
+}
+
+// Int64 returns a Field holding an int64 without allocation.
+func Int64(key string, value int64) Field {
+	return Field{Key: key, kind: int64Kind, num: value}
+}
+
+// Str returns a Field holding a string without allocation.
+func Str(key string, value string) Field {
+	return Field{Key: key, kind: stringKind, str: value}
+}
+
+// Dur returns a Field holding a time.Duration without allocation.
+func Dur(key string, value time.Duration) Field {
+	return Field{Key: key, kind: durationKind, num: int64(value)}
+}
+
+// Bool returns a Field holding a bool without allocation.
+func Bool(key string, value bool) Field {
+	f := Field{Key: key, kind: boolKind}
+	if value {
+		f.num = 1
+	}
+	return f
+}
+
+// valueKind returns the kind of the value of the field, Value overrides the typed value.
+func (f *Field) valueKind() fieldKind {
+	if f.Value != nil {
+		return anyKind
+	}
+	return f.kind
+}
+
+// Any returns the value of the field, the value of the typed constructors is boxed with its
+// type, e.g. int for Int.
+func (f Field) Any() any {
+	switch f.valueKind() {
+	case intKind:
+		return int(f.num)
+	case int64Kind:
+		return f.num
+	case stringKind:
+		return f.str
+	case durationKind:
+		return time.Duration(f.num)
+	case boolKind:
+		return f.num != 0
+	default:
+		return f.Value
+	}
+}
+
+// String implements the fmt.Stringer interface and returns the field as `key=value`.
+func (f Field) String() string {
+	return string(f.appendText(nil))
+}
+
+// appendText appends the `key=value` form of the field to b, quoting the key and the value
+// if needed.
+func (f Field) appendText(b []byte) []byte {
+	if needsQuote(f.Key) {
+		b = strconv.AppendQuote(b, f.Key)
+	} else {
+		b = append(b, f.Key...)
+	}
+	b = append(b, '=')
+	return f.appendValue(b, true)
+}
+
+// appendValue appends the value of the field rendered as text to b, it is quoted if needed
+// and quote is set.
+func (f Field) appendValue(b []byte, quote bool) []byte {
+	var s string
+	switch f.valueKind() {
+	case intKind, int64Kind:
+		return strconv.AppendInt(b, f.num, 10)
+	case boolKind:
+		return strconv.AppendBool(b, f.num != 0)
+	case durationKind:
+		return append(b, time.Duration(f.num).String()...)
+	case stringKind:
+		s = f.str
+	default:
+		switch v := f.Value.(type) {
+		case string:
+			s = v
+		case error:
+			s = v.Error()
+		case fmt.Stringer:
+			s = v.String()
+		default:
+			s = fmt.Sprint(v)
+		}
+	}
+	if quote && needsQuote(s) {
+		return strconv.AppendQuote(b, s)
+	}
+	return append(b, s...)
+}
+
+// needsQuote reports whether s must be quoted to be rendered as a single field key or value.
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	return strings.IndexFunc(s, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == 0x7f
+	}) != -1
+}
+
+// Fields converts alternating keys and values to fields like the *w methods of the loggers,
+// it allows other Logger implementations to share the same conversion rules.
+func Fields(keysAndValues ...any) []Field {
+	return toFields(keysAndValues)
+}
+
+// toFields converts alternating keys and values to fields.
+// A Field argument is used as is, a value without a string key is recorded under `!BADKEY`
+// and a trailing key without value is recorded with the value `(MISSING)`.
+func toFields(keysAndValues []any) []Field {
+	if len(keysAndValues) == 0 {
+		return nil
+	}
+	fields := make([]Field, 0, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i++ {
+		switch key := keysAndValues[i].(type) {
+		case Field:
+			fields = append(fields, key)
+		case string:
+			if i+1 < len(keysAndValues) {
+				fields = append(fields, Field{Key: key, Value: keysAndValues[i+1]})
+				i++
+			} else {
+				fields = append(fields, Field{Key: key, Value: missingValue})
+			}
+		default:
+			fields = append(fields, Field{Key: badKey, Value: key})
+		}
+	}
+	return fields
+}
+
+// joinFields returns a new slice containing the fields of a followed by the fields of b.
+// It never shares the underlying array with a, so that child loggers cannot overwrite
+// the fields of their parent.
+func joinFields(a, b []Field) []Field {
+	if len(b) == 0 {
+		return a
+	}
+	if len(a) == 0 {
+		return b
+	}
+	fields := make([]Field, 0, len(a)+len(b))
+	fields = append(fields, a...)
+	return append(fields, b...)
+}
+
+// appendFields appends fields separated by a space to b.
+func appendFields(b []byte, fields []Field) []byte {
+	for index := range fields {
+		b = append(b, ' ')
+		b = fields[index].appendText(b)
+	}
+	return b
+}
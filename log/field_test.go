@@ -0,0 +1,88 @@
+package log
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestToFields(t *testing.T) {
+	cases := []struct {
+		name string
+		kvs  []any
+		want []Field
+	}{
+		{"empty", nil, nil},
+		{"pairs", []any{"a", 1, "b", "x"}, []Field{{Key: "a", Value: 1}, {Key: "b", Value: "x"}}},
+		{"missing value", []any{"a", 1, "b"}, []Field{{Key: "a", Value: 1}, {Key: "b", Value: missingValue}}},
+		{"bad key", []any{1, "a", 2}, []Field{{Key: badKey, Value: 1}, {Key: "a", Value: 2}}},
+		{"field", []any{Field{Key: "f", Value: 1}, "a", 2}, []Field{{Key: "f", Value: 1}, {Key: "a", Value: 2}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.want, toFields(c.kvs))
+		})
+	}
+}
+
+func TestFieldString(t *testing.T) {
+	cases := []struct {
+		field Field
+		want  string
+	}{
+		{Field{Key: "k", Value: "v"}, "k=v"},
+		{Field{Key: "k", Value: ""}, `k=""`},
+		{Field{Key: "k", Value: "a b"}, `k="a b"`},
+		{Field{Key: "k", Value: "a=b"}, `k="a=b"`},
+		{Field{Key: "k", Value: "line\n"}, `k="line\n"`},
+		{Field{Key: "k", Value: 3.5}, "k=3.5"},
+		{Field{Key: "k", Value: nil}, "k=<nil>"},
+		{Field{Key: "k", Value: errors.New("boom")}, "k=boom"},
+		{Field{Key: "k", Value: time.Second}, "k=1s"},
+		{Int("k", -3), "k=-3"},
+		{Int64("k", 1<<40), "k=1099511627776"},
+		{Str("k", "v"), "k=v"},
+		{Str("k", "a b"), `k="a b"`},
+		{Dur("k", 1500*time.Millisecond), "k=1.5s"},
+		{Bool("k", true), "k=true"},
+		{Str("my key", "v"), `"my key"=v`},
+		{Str("a=b", "v"), `"a=b"=v`},
+		{Str("k\n", "v"), `"k\n"=v`},
+		{Str("", "v"), `""=v`},
+	}
+	for _, c := range cases {
+		t.Run(c.want, func(t *testing.T) {
+			require.Equal(t, c.want, c.field.String())
+		})
+	}
+}
+
+func TestTypedFields(t *testing.T) {
+	require.Equal(t, 3, Int("k", 3).Any())
+	require.Equal(t, int64(-1), Int64("k", -1).Any())
+	require.Equal(t, "v", Str("k", "v").Any())
+	require.Equal(t, time.Second, Dur("k", time.Second).Any())
+	require.Equal(t, false, Bool("k", false).Any())
+	require.Equal(t, 1, Field{Key: "k", Value: 1}.Any())
+	require.Nil(t, Str("k", "v").Value)
+
+	// Value replaces the typed value, e.g. when a hook redacts it
+	redacted := Str("password", "secret")
+	redacted.Value = "***"
+	require.Equal(t, "***", redacted.Any())
+	require.Equal(t, "password=***", redacted.String())
+	require.Equal(t, `"***"`, string(appendJSONField(nil, &redacted)))
+}
+
+func TestJoinFields(t *testing.T) {
+	parent := make([]Field, 1, 4)
+	parent[0] = Field{Key: "a", Value: 1}
+	left := joinFields(parent, []Field{{Key: "b", Value: 2}})
+	right := joinFields(parent, []Field{{Key: "c", Value: 3}})
+	require.Equal(t, []Field{{Key: "a", Value: 1}, {Key: "b", Value: 2}}, left)
+	require.Equal(t, []Field{{Key: "a", Value: 1}, {Key: "c", Value: 3}}, right)
+	require.Equal(t, parent, joinFields(parent, nil))
+	require.Equal(t, right[1:], joinFields(nil, right[1:]))
+}
@@ -0,0 +1,188 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/stkali/utility/errors"
+)
+
+// maxLevelRequestSize limits the size of the body of a PUT request to the LevelHandler.
+const maxLevelRequestSize = 1 << 16
+
+// LevelHandler is an http.Handler that reads and changes the level of the standard logger
+// and the level rules of the named loggers at runtime.
+//
+// GET returns the current levels:
+//
+//	{"level":"WARN","levels":{"storage":"DEBUG"}}
+//
+// PUT changes them, `level` is set with SetLevel, which also applies to the loggers created
+// by Named and With before, and `levels` replaces the rules set by SetLevels (an empty
+// object removes them), omitted keys are not changed. With a `ttl`,
+// the levels before the change are restored when it expires:
+//
+//	curl -X PUT -d '{"level":"debug","levels":{"storage":"trace"},"ttl":"5m"}' localhost:8080/log/level
+//
+// The response of PUT is the same as GET, `revert_at` is the time at which the levels
+// will be restored.
+type LevelHandler struct {
+	mtx sync.Mutex
+	// saved are the levels restored when revert fires, nil means no pending revert.
+	saved *levelState
+	// revert is the timer of the pending revert, generation identifies it so that a timer
+	// that fired while a later request replaced it does nothing.
+	revert     *time.Timer
+	revertAt   time.Time
+	generation int
+}
+
+var _ http.Handler = (*LevelHandler)(nil)
+
+// levelState is a snapshot of the levels.
+type levelState struct {
+	level Level
+	rules map[string]Level
+}
+
+// levelsRequest is the body of a PUT request.
+type levelsRequest struct {
+	Level  string            `json:"level"`
+	Levels map[string]string `json:"levels"`
+	TTL    string            `json:"ttl"`
+}
+
+// levelsResponse is the body of the responses.
+type levelsResponse struct {
+	Level    string            `json:"level"`
+	Levels   map[string]string `json:"levels"`
+	RevertAt string            `json:"revert_at,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// NewLevelHandler returns a new LevelHandler.
+func NewLevelHandler() *LevelHandler {
+	return &LevelHandler{}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *LevelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.writeState(w, http.StatusOK, "")
+	case http.MethodPut:
+		req := levelsRequest{}
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxLevelRequestSize))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			h.writeState(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+		if err := h.apply(&req); err != nil {
+			h.writeState(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.writeState(w, http.StatusOK, "")
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		h.writeState(w, http.StatusMethodNotAllowed, "method "+r.Method+" not allowed")
+	}
+}
+
+// apply validates the request and changes the levels.
+func (h *LevelHandler) apply(req *levelsRequest) error {
+	var level Level
+	if req.Level != "" {
+		lv, ok := parseLevel(req.Level)
+		if !ok {
+			return errors.Newf("invalid level %q", req.Level)
+		}
+		level = lv
+	}
+	var rules map[string]Level
+	if req.Levels != nil {
+		rules = make(map[string]Level, len(req.Levels))
+		for name, value := range req.Levels {
+			if !validRuleName(name) {
+				return errors.Newf("invalid logger name %q", name)
+			}
+			lv, ok := parseLevel(value)
+			if !ok {
+				return errors.Newf("invalid level %q of logger %q", value, name)
+			}
+			rules[name] = lv
+		}
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+			return errors.Newf("invalid ttl %q", req.TTL)
+		}
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.cancelRevert(ttl > 0)
+	if ttl > 0 {
+		if h.saved == nil {
+			h.saved = &levelState{level: GetLevel(), rules: Levels()}
+		}
+		h.generation++
+		generation := h.generation
+		h.revertAt = time.Now().Add(ttl)
+		h.revert = time.AfterFunc(ttl, func() {
+			h.mtx.Lock()
+			defer h.mtx.Unlock()
+			if h.generation == generation && h.saved != nil {
+				SetLevel(h.saved.level)
+				storeLevels(h.saved.rules)
+				h.cancelRevert(false)
+			}
+		})
+	}
+	if req.Level != "" {
+		SetLevel(level)
+	}
+	if rules != nil {
+		storeLevels(rules)
+	}
+	return nil
+}
+
+// cancelRevert stops the pending revert, the saved levels are kept if keepSaved is set so
+// that a following change with a ttl restores the levels before the first change.
+// h.mtx must be held.
+func (h *LevelHandler) cancelRevert(keepSaved bool) {
+	if h.revert != nil {
+		h.revert.Stop()
+		h.revert = nil
+	}
+	h.generation++
+	h.revertAt = time.Time{}
+	if !keepSaved {
+		h.saved = nil
+	}
+}
+
+// writeState writes the current levels as JSON with the status code and error message.
+func (h *LevelHandler) writeState(w http.ResponseWriter, code int, msg string) {
+	resp := levelsResponse{
+		Level:  GetLevel().Name(),
+		Levels: make(map[string]string),
+		Error:  msg,
+	}
+	for name, lv := range Levels() {
+		resp.Levels[name] = lv.Name()
+	}
+	h.mtx.Lock()
+	if !h.revertAt.IsZero() {
+		resp.RevertAt = h.revertAt.Format(time.RFC3339)
+	}
+	h.mtx.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(&resp)
+}
@@ -0,0 +1,128 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// doLevelRequest sends a request to the handler and decodes the response.
+func doLevelRequest(t *testing.T, handler http.Handler, method, body string) (int, levelsResponse) {
+	req := httptest.NewRequest(method, "/log/level", strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	resp := levelsResponse{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	return recorder.Code, resp
+}
+
+func TestLevelHandler(t *testing.T) {
+	SetLevel(WARN)
+	defer SetLevel(defaultLevel)
+	defer func() { _ = SetLevels("") }()
+	require.NoError(t, SetLevels("storage=info"))
+	handler := NewLevelHandler()
+
+	t.Run("get", func(t *testing.T) {
+		code, resp := doLevelRequest(t, handler, http.MethodGet, "")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, levelsResponse{Level: "WARN", Levels: map[string]string{"storage": "INFO"}}, resp)
+	})
+
+	t.Run("put", func(t *testing.T) {
+		code, resp := doLevelRequest(t, handler, http.MethodPut, `{"level":"debug"}`)
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, levelsResponse{Level: "DEBUG", Levels: map[string]string{"storage": "INFO"}}, resp)
+		require.Equal(t, DEBUG, GetLevel())
+
+		code, resp = doLevelRequest(t, handler, http.MethodPut, `{"levels":{"net.http":"error","*":"info"}}`)
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, levelsResponse{Level: "DEBUG", Levels: map[string]string{"net.http": "ERROR", "*": "INFO"}}, resp)
+		require.Equal(t, "*=info,net.http=error", FormatLevels(Levels()))
+
+		code, resp = doLevelRequest(t, handler, http.MethodPut, `{"levels":{}}`)
+		require.Equal(t, http.StatusOK, code)
+		require.Empty(t, resp.Levels)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, body := range []string{
+			`{"level":"loud"}`,
+			`{"levels":{".storage":"info"}}`,
+			`{"levels":{"storage":"loud"}}`,
+			`{"levels":{"a=debug,b":"info"}}`,
+			`{"levels":{"":"info"}}`,
+			`{"ttl":"soon"}`,
+			`{"ttl":"-1s"}`,
+			`{"unknown":1}`,
+			`level=info`,
+		} {
+			code, resp := doLevelRequest(t, handler, http.MethodPut, body)
+			require.Equal(t, http.StatusBadRequest, code, body)
+			require.NotEmpty(t, resp.Error)
+			require.Equal(t, "DEBUG", resp.Level)
+			require.Empty(t, resp.Levels)
+		}
+		code, resp := doLevelRequest(t, handler, http.MethodPost, `{"level":"info"}`)
+		require.Equal(t, http.StatusMethodNotAllowed, code)
+		require.Equal(t, "method POST not allowed", resp.Error)
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		SetLevel(WARN)
+		require.NoError(t, SetLevels("storage=info"))
+		code, resp := doLevelRequest(t, handler, http.MethodPut, `{"level":"trace","levels":{"storage":"trace"},"ttl":"1h"}`)
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "TRACE", resp.Level)
+		revertAt, err := time.Parse(time.RFC3339, resp.RevertAt)
+		require.NoError(t, err)
+		require.WithinDuration(t, time.Now().Add(time.Hour), revertAt, time.Minute)
+
+		// a second change with a ttl restores the levels before the first one
+		code, resp = doLevelRequest(t, handler, http.MethodPut, `{"level":"debug","ttl":"50ms"}`)
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "DEBUG", resp.Level)
+		require.Eventually(t, func() bool {
+			return GetLevel() == WARN
+		}, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, "storage=info", FormatLevels(Levels()))
+		_, resp = doLevelRequest(t, handler, http.MethodGet, "")
+		require.Empty(t, resp.RevertAt)
+	})
+
+	t.Run("existing loggers", func(t *testing.T) {
+		SetLevel(WARN)
+		require.NoError(t, SetLevels(""))
+		// created before the change, like the package-level loggers of a running service
+		storage := Named("storage").(*defaultLogger)
+		request := With("request", "r-1").(*defaultLogger)
+		require.False(t, storage.Enabled(DEBUG))
+		require.False(t, request.Enabled(DEBUG))
+
+		code, _ := doLevelRequest(t, handler, http.MethodPut, `{"level":"debug","ttl":"50ms"}`)
+		require.Equal(t, http.StatusOK, code)
+		require.True(t, storage.Enabled(DEBUG))
+		require.True(t, request.Enabled(DEBUG))
+
+		require.Eventually(t, func() bool {
+			return !storage.Enabled(DEBUG)
+		}, 5*time.Second, 10*time.Millisecond)
+		require.False(t, request.Enabled(DEBUG))
+		require.True(t, storage.Enabled(WARN))
+	})
+
+	t.Run("put cancels ttl", func(t *testing.T) {
+		doLevelRequest(t, handler, http.MethodPut, `{"level":"trace","ttl":"50ms"}`)
+		code, resp := doLevelRequest(t, handler, http.MethodPut, `{"level":"error"}`)
+		require.Equal(t, http.StatusOK, code)
+		require.Empty(t, resp.RevertAt)
+		time.Sleep(100 * time.Millisecond)
+		require.Equal(t, ERROR, GetLevel())
+	})
+}
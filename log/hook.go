@@ -0,0 +1,38 @@
+package log
+
+// Hook is a stage of the entry pipeline of a logger. Hooks run in the order they have been
+// added, after the entry has been built and before it is sampled, encoded and written.
+//
+// Fire may inspect and modify the entry, e.g. redact field values, add fields or change the
+// level or message, and returns false to drop the entry, the following hooks are not run.
+// The Fields of the entry can be modified in place, they are not shared with the logger.
+// Dropping a FATAL entry does not prevent the exit.
+//
+// The entry is reused once the log call returns, hooks must not retain it.
+//
+// Hooks are called concurrently by the goroutines that log and must be safe for concurrent use.
+type Hook interface {
+	Fire(entry *Entry) bool
+}
+
+// HookFunc is an adapter to allow the use of ordinary functions as hooks.
+type HookFunc func(entry *Entry) bool
+
+// Fire implements the Hook interface.
+func (f HookFunc) Fire(entry *Entry) bool {
+	return f(entry)
+}
+
+// fireHooks runs the hooks on the entry and reports whether the entry must be written.
+func fireHooks(hooks []Hook, entry *Entry) bool {
+	if len(hooks) == 0 {
+		return true
+	}
+	entry.Fields = append(make([]Field, 0, len(entry.Fields)), entry.Fields...)
+	for _, hook := range hooks {
+		if !hook.Fire(entry) {
+			return false
+		}
+	}
+	return true
+}
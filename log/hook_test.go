@@ -0,0 +1,92 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks(t *testing.T) {
+	recorder := new(bytes.Buffer)
+	SetOutput(recorder)
+	SetLevel(TRACE)
+	SetFlags(0)
+	SetPrefix("")
+	defer func() {
+		SetHooks()
+		SetLevel(defaultLevel)
+		SetOutput(os.Stdout)
+	}()
+
+	var errorCount int64
+	redact := HookFunc(func(entry *Entry) bool {
+		for index := range entry.Fields {
+			if entry.Fields[index].Key == "password" {
+				entry.Fields[index].Value = "***"
+			}
+		}
+		return true
+	})
+	enrich := HookFunc(func(entry *Entry) bool {
+		entry.Fields = append(entry.Fields, Field{Key: "pid", Value: 42})
+		return true
+	})
+	count := HookFunc(func(entry *Entry) bool {
+		if entry.Level >= ERROR {
+			atomic.AddInt64(&errorCount, 1)
+		}
+		return true
+	})
+	drop := HookFunc(func(entry *Entry) bool {
+		return !strings.HasPrefix(entry.Message, "health")
+	})
+	AddHook(redact, enrich)
+	AddHook(count, drop)
+
+	t.Run("mutate and enrich", func(t *testing.T) {
+		recorder.Reset()
+		Infow("login", "user", "alice", "password", "secret")
+		require.Equal(t, INFO.String()+"login user=alice password=*** pid=42\n", recorder.String())
+	})
+
+	t.Run("bound fields are not modified", func(t *testing.T) {
+		child := With("password", "secret")
+		recorder.Reset()
+		child.Warn("retry")
+		require.Equal(t, WARN.String()+"retry password=*** pid=42\n", recorder.String())
+		require.Equal(t, "secret", child.(*defaultLogger).fields[0].Value)
+		require.Len(t, child.(*defaultLogger).fields, 1)
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		recorder.Reset()
+		Error("healthcheck failed")
+		Errorf("request %d failed", 1)
+		require.Equal(t, ERROR.String()+"request 1 failed pid=42\n", recorder.String())
+		require.Equal(t, int64(2), atomic.LoadInt64(&errorCount))
+	})
+
+	t.Run("caller and level", func(t *testing.T) {
+		var caller Caller
+		SetHooks(HookFunc(func(entry *Entry) bool {
+			caller = entry.Caller
+			entry.Level = ERROR
+			return true
+		}))
+		recorder.Reset()
+		Debug("upgraded")
+		require.Equal(t, ERROR.String()+"upgraded\n", recorder.String())
+		require.Equal(t, "hook_test.go", caller.shortFile())
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		SetHooks()
+		recorder.Reset()
+		Infow("login", "password", "secret")
+		require.Equal(t, INFO.String()+"login password=secret\n", recorder.String())
+	})
+}
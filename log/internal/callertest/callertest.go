@@ -0,0 +1,22 @@
+// Copyright 2021-2024 The utility Authors. All rights reserved.
+// Use of this source code is governed by the MIT license that can be found in the
+// LICENSE file
+
+// Package callertest provides the fixtures shared by the tests checking the caller reported
+// by the loggers. The tests log the line returned by Line and compare it with the caller.
+package callertest
+
+import "runtime"
+
+// Line returns the line of its caller, it is passed as an argument of the log call whose
+// caller is checked.
+func Line() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+// Helper calls fn with the args like the logging helpers of an application, a logger
+// skipping one more frame reports the line calling Helper.
+func Helper(fn func(...any), args ...any) {
+	fn(args...)
+}
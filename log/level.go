@@ -0,0 +1,107 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/stkali/utility/errors"
+)
+
+// severityStep is the severity distance between two consecutive built-in levels, the
+// severity of TRACE is 0 and the severity of FATAL is 500.
+const severityStep = 100
+
+// levelInfo describes a level registered by RegisterLevel.
+type levelInfo struct {
+	name     string
+	tag      string
+	severity int
+}
+
+var (
+	// customLevels holds the []levelInfo of the registered levels, the level FATAL+1+i is
+	// described by the i-th element. The slice is never modified after it has been stored.
+	customLevels atomic.Value
+	// customLevelsMtx serializes the writers of customLevels.
+	customLevelsMtx sync.Mutex
+)
+
+// lookupCustomLevel returns the description of a level registered by RegisterLevel.
+func lookupCustomLevel(l Level) (levelInfo, bool) {
+	infos, _ := customLevels.Load().([]levelInfo)
+	if index := int(l) - int(FATAL) - 1; index >= 0 && index < len(infos) {
+		return infos[index], true
+	}
+	return levelInfo{}, false
+}
+
+// findCustomLevel returns the registered level with the case-insensitive name.
+func findCustomLevel(name string) (Level, bool) {
+	infos, _ := customLevels.Load().([]levelInfo)
+	for index := range infos {
+		if strings.EqualFold(infos[index].name, name) {
+			return FATAL + 1 + Level(index), true
+		}
+	}
+	return 0, false
+}
+
+// RegisterLevel registers a new level with the name used by ToLevel and SetLevels, the
+// severity used to order it among the other levels and the text rendered in the level tag,
+// e.g. "NOTE " for "[NOTE ] ". An empty text renders the upper-case name padded to 5 characters.
+//
+// The severity of the built-in levels is 0 for TRACE, 100 for DEBUG, 200 for INFO, 300 for
+// WARN, 400 for ERROR and 500 for FATAL, e.g. a NOTICE level between INFO and WARN:
+//
+//	NOTICE, _ := log.RegisterLevel("notice", 250, "")
+//	log.Log(NOTICE, "disk usage above 80%")
+//
+// Levels are process-wide and should be registered during initialization, it returns an
+// error if the name is empty or already used.
+func RegisterLevel(name string, severity int, text string) (Level, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, ",= ") {
+		return 0, errors.Newf("invalid level name %q", name)
+	}
+	if text == "" {
+		text = strings.ToUpper(name)
+		if len(text) < 5 {
+			text += strings.Repeat(" ", 5-len(text))
+		}
+	}
+	customLevelsMtx.Lock()
+	defer customLevelsMtx.Unlock()
+	if _, ok := parseLevel(name); ok {
+		return 0, errors.Newf("level %q is already registered", name)
+	}
+	infos, _ := customLevels.Load().([]levelInfo)
+	infos = append(infos[:len(infos):len(infos)], levelInfo{
+		name:     strings.ToUpper(name),
+		tag:      "[" + text + "] ",
+		severity: severity,
+	})
+	customLevels.Store(infos)
+	return FATAL + Level(len(infos)), nil
+}
+
+// Severity returns the severity of the level which orders the levels: a logger writes the
+// entries whose level has a severity greater than or equal to the severity of its level.
+// Levels that are neither built-in nor registered have the severity int(l)*100.
+func (l Level) Severity() int {
+	if l > FATAL {
+		if info, ok := lookupCustomLevel(l); ok {
+			return info.severity
+		}
+	}
+	return int(l) * severityStep
+}
+
+// atLeast reports whether the severity of lv is greater than or equal to the severity
+// of threshold.
+func atLeast(lv, threshold Level) bool {
+	if lv >= TRACE && lv <= FATAL && threshold >= TRACE && threshold <= FATAL {
+		return lv >= threshold
+	}
+	return lv.Severity() >= threshold.Severity()
+}
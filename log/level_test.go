@@ -0,0 +1,81 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterLevel(t *testing.T) {
+	defer func() { customLevels = atomic.Value{} }()
+
+	NOTICE, err := RegisterLevel("notice", 250, "")
+	require.NoError(t, err)
+	AUDIT, err := RegisterLevel("Audit", 350, "AUDIT")
+	require.NoError(t, err)
+	require.Equal(t, FATAL+1, NOTICE)
+	require.Equal(t, FATAL+2, AUDIT)
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, name := range []string{"", "  ", "a=b", "info", "Warning", "NOTICE"} {
+			_, err := RegisterLevel(name, 100, "")
+			require.Error(t, err, name)
+		}
+	})
+
+	t.Run("names", func(t *testing.T) {
+		require.Equal(t, "[NOTICE] ", NOTICE.String())
+		require.Equal(t, "[AUDIT] ", AUDIT.String())
+		require.Equal(t, "NOTICE", NOTICE.Name())
+		require.Equal(t, "[Level(100)]", Level(100).String())
+		require.Equal(t, NOTICE, ToLevel("Notice"))
+		require.Equal(t, AUDIT, ToLevel("audit"))
+		require.Equal(t, AUDIT, ToLevel(int(AUDIT)))
+		rules, err := ParseLevels("storage=notice")
+		require.NoError(t, err)
+		require.Equal(t, "storage=notice", FormatLevels(rules))
+	})
+
+	t.Run("severity", func(t *testing.T) {
+		require.Equal(t, 0, TRACE.Severity())
+		require.Equal(t, 500, FATAL.Severity())
+		require.Equal(t, 250, NOTICE.Severity())
+		require.Equal(t, -100, Level(-1).Severity())
+		require.True(t, atLeast(NOTICE, INFO))
+		require.False(t, atLeast(NOTICE, WARN))
+		require.True(t, atLeast(AUDIT, NOTICE))
+		require.True(t, atLeast(ERROR, AUDIT))
+	})
+
+	t.Run("filtering", func(t *testing.T) {
+		recorder := new(bytes.Buffer)
+		SetOutput(recorder)
+		SetFlags(0)
+		SetPrefix("")
+		defer SetOutput(os.Stdout)
+		defer SetLevel(defaultLevel)
+
+		SetLevel(NOTICE)
+		Info("hidden")
+		Log(NOTICE, "notice")
+		Logf(AUDIT, "audit %d", 1)
+		Logw(INFO, "hidden")
+		Warn("warn")
+		require.Equal(t, "[NOTICE] notice\n[AUDIT] audit 1\n"+WARN.String()+"warn\n", recorder.String())
+
+		recorder.Reset()
+		SetLevel(WARN)
+		Log(NOTICE, "hidden")
+		Logw(AUDIT, "audit", "user", "alice")
+		require.Equal(t, "[AUDIT] audit user=alice\n", recorder.String())
+
+		recorder.Reset()
+		SetEncoder(NewJSONEncoder())
+		defer SetEncoder(nil)
+		Log(AUDIT, "json")
+		require.Contains(t, recorder.String(), `"level":"AUDIT"`)
+	})
+}
@@ -3,17 +3,24 @@
 // LICENSE file
 
 // Package log provides a simple logging interface with levels.
-// It is based on the standard log package and provides additional levels like TRACE,
-// DEBUG, INFO, WARN, ERROR, and FATAL.
+// It is compatible with the output of the standard log package and provides additional
+// levels like TRACE, DEBUG, INFO, WARN, ERROR, and FATAL, structured fields and pluggable
+// encoders.
 
 package log
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"log"
 	"os"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/stkali/utility/errors"
 )
 
 const (
@@ -33,10 +40,14 @@ const (
 	Lshortfile                    // final file name element and line number: d.go:23. overrides Llongfile
 	LUTC                          // if Ldate or Ltime is set, use UTC rather than the local time zone
 	Lmsgprefix                    // move the "prefix" from the beginning of the line to before the message
+	Lfuncname                     // function name of the caller after the file name: d.go:23 main.handle. shortened unless Llongfile
 	LstdFlags     = Ldate | Ltime // initial values for the standard logger
 )
 
-var Exit = os.Exit
+// Exit exits the program after FATAL, it routes through errors.ExitMessage so that the hook
+// set by errors.SetExitHook is called with the message of the entry and errors.ReplaceExit
+// applies. See SetFatalPolicy.
+var Exit = exitFatal
 
 type Level int
 
@@ -46,9 +57,23 @@ func (l Level) String() string {
 	if l >= TRACE && l <= FATAL {
 		return levels[l]
 	}
+	if info, ok := lookupCustomLevel(l); ok {
+		return info.tag
+	}
 	return fmt.Sprintf("[Level(%d)]", l)
 }
 
+// Name returns the bare name of the level, e.g. "INFO".
+func (l Level) Name() string {
+	if l >= TRACE && l <= FATAL {
+		return levelNames[l]
+	}
+	if info, ok := lookupCustomLevel(l); ok {
+		return info.name
+	}
+	return fmt.Sprintf("Level(%d)", l)
+}
+
 // ToLevel converts a string, int, or Level to a Level type.
 // It handles conversions like .
 // ToLevel(1)         -> INFO
@@ -74,28 +99,48 @@ func ToLevelWithDefault(level any, def Level) Level {
 	}
 }
 
-// string2Level returns Level when the paramter `level` lower is a standard level string else
-// defaultLevel (WARN)
+// string2Level returns Level when the paramter `level` lower is a standard level string or
+// a registered level name else defaultLevel (WARN)
 func string2Level(level string) Level {
+	if lv, ok := parseLevel(level); ok {
+		return lv
+	}
+	return defaultLevel
+}
+
+// parseLevel returns the Level named by the case-insensitive string and whether the name
+// is a standard level string or the name of a level registered by RegisterLevel.
+func parseLevel(level string) (Level, bool) {
 	switch strings.ToLower(level) {
 	case "trace":
-		return TRACE
+		return TRACE, true
 	case "debug":
-		return DEBUG
+		return DEBUG, true
 	case "info":
-		return INFO
+		return INFO, true
 	case "warning", "warn":
-		return WARN
+		return WARN, true
 	case "error", "err":
-		return ERROR
+		return ERROR, true
 	case "fatal":
-		return FATAL
+		return FATAL, true
 	default:
-		return defaultLevel
+		if lv, ok := findCustomLevel(level); ok {
+			return lv, true
+		}
+		return defaultLevel, false
 	}
 }
 
 var (
+	levelNames = []string{
+		"TRACE",
+		"DEBUG",
+		"INFO",
+		"WARN",
+		"ERROR",
+		"FATAL",
+	}
 	levels = []string{
 		"[TRACE] ",
 		"[DEBUG] ",
@@ -104,7 +149,7 @@ var (
 		"[ERROR] ",
 		"[FATAL] ",
 	}
-	defaultFlags  = log.LstdFlags | log.Lshortfile | log.Lmicroseconds
+	defaultFlags  = LstdFlags | Lshortfile | Lmicroseconds
 	defaultPrefix = ""
 	defaultLevel  = WARN
 )
@@ -123,46 +168,466 @@ type Logger interface {
 	Warnf(format string, args ...any)
 	Errorf(format string, args ...any)
 	Fatalf(format string, args ...any)
+	Tracew(msg string, keysAndValues ...any)
+	Debugw(msg string, keysAndValues ...any)
+	Infow(msg string, keysAndValues ...any)
+	Warnw(msg string, keysAndValues ...any)
+	Errorw(msg string, keysAndValues ...any)
+	Fatalw(msg string, keysAndValues ...any)
+	Log(level Level, args ...any)
+	Logf(level Level, format string, args ...any)
+	Logw(level Level, msg string, keysAndValues ...any)
+	With(keysAndValues ...any) Logger
+	Named(name string) Logger
+	WithContext(ctx context.Context) Logger
 	SetLevel(Level)
 	SetOutput(io.Writer)
 	SetPrefix(prefix string)
 	SetFlags(flag int)
+	SetEncoder(encoder Encoder)
+}
+
+// core is the output state shared by a logger and the children created by With.
+type core struct {
+	mtx     sync.Mutex
+	out     io.Writer
+	prefix  string
+	flags   int
+	encoder Encoder
+	// sampler suppresses entries according to SetSampling, nil means no sampling.
+	sampler *sampler
+	// traceback renders the logged errors according to SetTraceback, nil disables it.
+	traceback *Traceback
+	// hooks are run on every entry, the slice is replaced and never modified so that it can
+	// be used without holding mtx.
+	hooks []Hook
+}
+
+func newCore(out io.Writer, prefix string, flags int, encoder Encoder) *core {
+	return &core{
+		out:     out,
+		prefix:  prefix,
+		flags:   flags,
+		encoder: encoder,
+	}
+}
+
+// write samples the entry, then encodes it and writes it to the output.
+func (c *core) write(entry *Entry) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.sampler != nil {
+		allowed, suppressed := c.sampler.sample(entry)
+		if suppressed > 0 {
+			report := summary(entry, suppressed)
+			c.encode(&report)
+		}
+		if !allowed {
+			c.armSampler()
+			return
+		}
+	}
+	c.encode(entry)
+}
+
+// armSampler starts the timer reporting the entries suppressed by the sampler at the end of
+// the tick, c.mtx must be held.
+func (c *core) armSampler() {
+	s := c.sampler
+	if s.timer != nil {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(s.windowEnd), func() {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		if c.sampler == s && s.timer == timer {
+			c.reportSuppressed()
+		}
+	})
+	s.timer = timer
+}
+
+// reportSuppressed writes the summary of the entries suppressed by the sampler, c.mtx must
+// be held.
+func (c *core) reportSuppressed() {
+	if c.sampler == nil {
+		return
+	}
+	if last, suppressed := c.sampler.lastSuppressed, c.sampler.flush(); suppressed > 0 {
+		last.Time = time.Now()
+		report := summary(&last, suppressed)
+		c.encode(&report)
+	}
+}
+
+// encode encodes the entry and writes it to the output, c.mtx must be held.
+// Outputs implementing EntryWriter encode the entry themselves.
+func (c *core) encode(entry *Entry) {
+	entry.Prefix = c.prefix
+	entry.Flags = c.flags
+	if w, ok := c.out.(EntryWriter); ok {
+		_ = w.WriteEntry(entry, c.encoder)
+		return
+	}
+	buf := getBuffer()
+	*buf = c.encoder.Encode((*buf)[:0], entry)
+	_, _ = c.out.Write(*buf)
+	putBuffer(buf)
+}
+
+// flush reports the entries suppressed by the sampler, then flushes the output if it
+// implements Flusher, errors are reported as warnings.
+func (c *core) flush() {
+	c.mtx.Lock()
+	c.reportSuppressed()
+	out := c.out
+	c.mtx.Unlock()
+	if flusher, ok := out.(Flusher); ok {
+		errors.Warning(flusher.Flush())
+	}
+}
+
+// reset replaces the output settings of the core, the writes in flight are done with the
+// previous output when it returns. The previous output is flushed so that it can be closed.
+func (c *core) reset(out io.Writer, prefix string, flags int, encoder Encoder) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.reportSuppressed()
+	if flusher, ok := c.out.(Flusher); ok {
+		errors.Warning(flusher.Flush())
+	}
+	c.out, c.prefix, c.flags, c.encoder = out, prefix, flags, encoder
+}
+
+// settings returns whether the caller of entries is needed, the traceback settings and
+// the hooks.
+func (c *core) settings() (needCaller bool, traceback *Traceback, hooks []Hook) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.needCaller(), c.traceback, c.hooks
+}
+
+// tracebackEnabled reports whether SetTraceback has been set.
+func (c *core) tracebackEnabled() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.traceback != nil
+}
+
+// needCaller reports whether the encoder or the sampler uses the caller of entries,
+// c.mtx must be held.
+func (c *core) needCaller() bool {
+	if len(c.hooks) > 0 {
+		return true
+	}
+	if c.sampler != nil && c.sampler.option.By == ByCaller && c.sampler.option.First > 0 {
+		return true
+	}
+	if _, ok := c.out.(EntryWriter); ok {
+		return true
+	}
+	switch c.encoder.(type) {
+	case *TextEncoder, *ConsoleEncoder:
+		return c.flags&(Lshortfile|Llongfile|Lfuncname) != 0
+	}
+	return true
 }
 
 type defaultLogger struct {
-	stdLog *log.Logger
-	level  Level
+	core *core
+	// level is the Level threshold of the logger, it is accessed atomically. It is shared
+	// with the children created by With, Named, WithContext and WithCallerSkip, so that
+	// they follow SetLevel called on any of them unless a rule set by SetLevels applies.
+	level *int32
+	// name is the dotted name given by Named, it selects the level rules set by SetLevels.
+	name string
+	// fields are bound by With and rendered on every line of the logger.
+	fields []Field
+	// callerSkip is the number of additional frames skipped to find the caller, see
+	// WithCallerSkip.
+	callerSkip int
 }
 
 func (l *defaultLogger) SetPrefix(prefix string) {
-	l.stdLog.SetPrefix(prefix)
+	l.core.mtx.Lock()
+	defer l.core.mtx.Unlock()
+	l.core.prefix = prefix
 }
 
 func (l *defaultLogger) SetFlags(flag int) {
-	l.stdLog.SetFlags(flag)
+	l.core.mtx.Lock()
+	defer l.core.mtx.Unlock()
+	l.core.flags = flag
 }
 
 func (l *defaultLogger) SetOutput(w io.Writer) {
-	l.stdLog.SetOutput(w)
+	l.core.mtx.Lock()
+	defer l.core.mtx.Unlock()
+	l.core.out = w
+}
+
+// SetEncoder sets the encoder used to render entries, nil restores the TextEncoder.
+func (l *defaultLogger) SetEncoder(encoder Encoder) {
+	if encoder == nil {
+		encoder = NewTextEncoder()
+	}
+	l.core.mtx.Lock()
+	defer l.core.mtx.Unlock()
+	l.core.encoder = encoder
+}
+
+// SetSampling sets the sampling and rate limiting of the logger and the loggers sharing its
+// output, nil disables sampling.
+func (l *defaultLogger) SetSampling(sampling *Sampling) {
+	var s *sampler
+	if sampling != nil {
+		s = newSampler(*sampling)
+	}
+	l.core.mtx.Lock()
+	defer l.core.mtx.Unlock()
+	l.core.reportSuppressed()
+	l.core.sampler = s
+}
+
+// AddHook appends hooks to the pipeline of the logger and the loggers sharing its output.
+func (l *defaultLogger) AddHook(hooks ...Hook) {
+	l.core.mtx.Lock()
+	defer l.core.mtx.Unlock()
+	l.core.hooks = append(append(make([]Hook, 0, len(l.core.hooks)+len(hooks)), l.core.hooks...), hooks...)
 }
 
+// SetHooks replaces the pipeline of the logger and the loggers sharing its output with hooks,
+// no hooks removes all hooks.
+func (l *defaultLogger) SetHooks(hooks ...Hook) {
+	l.core.mtx.Lock()
+	defer l.core.mtx.Unlock()
+	l.core.hooks = append([]Hook(nil), hooks...)
+}
+
+// SetTraceback sets how the errors logged by the logger and the loggers sharing its output
+// are rendered, nil disables the rendering of stack traces and causes.
+func (l *defaultLogger) SetTraceback(traceback *Traceback) {
+	if traceback != nil {
+		cp := *traceback
+		traceback = &cp
+	}
+	l.core.mtx.Lock()
+	defer l.core.mtx.Unlock()
+	l.core.traceback = traceback
+}
+
+// SetLevel sets the level of the logger and of the loggers sharing it, it is safe to call
+// concurrently with logging.
 func (l *defaultLogger) SetLevel(lv Level) {
-	l.level = lv
+	atomic.StoreInt32(l.level, int32(lv))
+}
+
+// GetLevel returns the level set by SetLevel.
+func (l *defaultLogger) GetLevel() Level {
+	return Level(atomic.LoadInt32(l.level))
+}
+
+// newLevel returns the level shared by a logger and its children.
+func newLevel(lv Level) *int32 {
+	level := int32(lv)
+	return &level
+}
+
+// With returns a child logger that renders the given key/value pairs on every line
+// after its own message. The child shares the level, output, prefix, flags and encoder of
+// its parent.
+func (l *defaultLogger) With(keysAndValues ...any) Logger {
+	return &defaultLogger{
+		core:       l.core,
+		level:      l.level,
+		name:       l.name,
+		fields:     joinFields(l.fields, toFields(keysAndValues)),
+		callerSkip: l.callerSkip,
+	}
+}
+
+// WithContext returns a child logger carrying the fields of ctx, see ContextFields.
+// The logger itself is returned if ctx carries no fields.
+func (l *defaultLogger) WithContext(ctx context.Context) Logger {
+	return l.withContext(ctx)
+}
+
+func (l *defaultLogger) withContext(ctx context.Context) *defaultLogger {
+	fields := ContextFields(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+	return &defaultLogger{
+		core:       l.core,
+		level:      l.level,
+		name:       l.name,
+		fields:     joinFields(l.fields, fields),
+		callerSkip: l.callerSkip,
+	}
+}
+
+// Named returns a child logger whose name is the name of the logger and the given name
+// joined by a dot. The level of a named logger is overridden by the rules set by SetLevels.
+func (l *defaultLogger) Named(name string) Logger {
+	return &defaultLogger{
+		core:       l.core,
+		level:      l.level,
+		name:       joinName(l.name, name),
+		fields:     l.fields,
+		callerSkip: l.callerSkip,
+	}
+}
+
+// WithCallerSkip returns a child logger that skips n additional frames to find the caller
+// of its entries, so that the helpers wrapping the logger report the line calling them:
+//
+//	var helperLog = log.WithCallerSkip(1)
+//
+//	func logRequest(r *http.Request) {
+//		helperLog.Infow("request", "path", r.URL.Path)
+//	}
+func (l *defaultLogger) WithCallerSkip(n int) Logger {
+	return &defaultLogger{
+		core:       l.core,
+		level:      l.level,
+		name:       l.name,
+		fields:     l.fields,
+		callerSkip: l.callerSkip + n,
+	}
+}
+
+// Enabled reports whether the logger writes entries of the level, it can guard the
+// computation of expensive arguments.
+func (l *defaultLogger) Enabled(lv Level) bool {
+	if threshold, ok := LookupLevel(l.name); ok {
+		return atLeast(lv, threshold)
+	}
+	return atLeast(lv, l.GetLevel())
 }
 
 func (l *defaultLogger) logf(lv Level, format *string, args ...any) {
-	if lv < l.level {
+	if !l.Enabled(lv) {
 		return
 	}
-	msg := lv.String()
-	if format != nil {
-		msg += fmt.Sprintf(*format, args...)
+	var msg string
+	if s, ok := singleString(format, args); ok {
+		msg = s
 	} else {
-		msg += fmt.Sprint(args...)
+		values := args
+		if l.core.tracebackEnabled() {
+			values = plainErrors(args)
+		}
+		if format != nil {
+			msg = fmt.Sprintf(*format, values...)
+		} else {
+			msg = fmt.Sprint(values...)
+		}
+	}
+	l.output(getEntry(), lv, msg, l.fields, findError(args))
+}
+
+// singleString returns the message of a call without format whose only argument is a
+// string, it is used as is instead of being formatted.
+func singleString(format *string, args []any) (string, bool) {
+	if format != nil || len(args) != 1 {
+		return "", false
+	}
+	s, ok := args[0].(string)
+	return s, ok
+}
+
+func (l *defaultLogger) logw(lv Level, msg string, keysAndValues ...any) {
+	if !l.Enabled(lv) {
+		return
+	}
+	l.output(getEntry(), lv, msg, joinFields(l.fields, toFields(keysAndValues)), findError(keysAndValues))
+}
+
+// logFields logs the fields without converting them, they are copied into the pooled
+// entry so that the call does not allocate.
+func (l *defaultLogger) logFields(lv Level, msg string, fields []Field) {
+	if !l.Enabled(lv) {
+		return
+	}
+	p := getEntry()
+	p.fields = append(append(p.fields[:0], l.fields...), fields...)
+	l.output(p, lv, msg, p.fields, findFieldError(fields))
+}
+
+// LogwAt logs the message and the key/value pairs at the level like Logw, with the caller at
+// the program counter pc instead of the caller of LogwAt, e.g. the PC of a slog.Record
+// handled by an adapter. A zero pc logs no caller.
+func (l *defaultLogger) LogwAt(pc uintptr, level Level, msg string, keysAndValues ...any) {
+	if !l.Enabled(level) {
+		return
+	}
+	p := getEntry()
+	p.pc, p.atPC = pc, true
+	l.output(p, level, msg, joinFields(l.fields, toFields(keysAndValues)), findError(keysAndValues))
+}
+
+// callerSkip is the number of frames between output and the caller of the logger: output,
+// logf/logw/logFields and the Logger method or the package-level function, which both call
+// logf/logw/logFields directly.
+const callerSkip = 3
+
+// callerAt returns the location and the function of the caller skip frames above
+// callerAt. Unlike runtime.Caller it does not allocate.
+func callerAt(skip int) Caller {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+1, pcs[:]) == 0 {
+		return Caller{}
+	}
+	// the program counter is the return address, the call is at the previous instruction
+	pc := pcs[0] - 1
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return Caller{}
 	}
-	_ = l.stdLog.Output(4, msg)
+	file, line := fn.FileLine(pc)
+	return Caller{File: file, Line: line, Function: fn.Name()}
+}
+
+// callerOf returns the location and the function of the program counter pc returned by
+// runtime.Callers.
+func callerOf(pc uintptr) Caller {
+	if pc == 0 {
+		return Caller{}
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return Caller{File: frame.File, Line: frame.Line, Function: frame.Function}
+}
+
+// output fills the pooled entry with the message and fields and hands it to the core,
+// the entry is released once written. err is the error logged by the call, it is rendered
+// according to the Traceback settings.
+func (l *defaultLogger) output(p *pooledEntry, lv Level, msg string, fields []Field, err error) {
+	entry := &p.entry
+	entry.Time = time.Now()
+	entry.Level = lv
+	entry.Name = l.name
+	entry.Message = msg
+	entry.Fields = fields
+	needCaller, traceback, hooks := l.core.settings()
+	if needCaller {
+		if p.atPC {
+			entry.Caller = callerOf(p.pc)
+		} else {
+			entry.Caller = callerAt(callerSkip + l.callerSkip + 1)
+		}
+	}
+	if traceback != nil {
+		traceback.render(entry, err)
+	}
+	if fireHooks(hooks, entry) {
+		l.core.write(entry)
+	}
+	msg = entry.Message
+	putEntry(p)
 	if lv == FATAL {
-		Exit(1)
+		l.core.flush()
+		FatalExit(msg)
 	}
 }
 
@@ -214,102 +679,433 @@ func (l *defaultLogger) Tracef(format string, args ...any) {
 	l.logf(TRACE, &format, args...)
 }
 
-var logger Logger = &defaultLogger{
-	level:  WARN,
-	stdLog: log.New(os.Stdout, defaultPrefix, defaultFlags),
+func (l *defaultLogger) Fatalw(msg string, keysAndValues ...any) {
+	l.logw(FATAL, msg, keysAndValues...)
+}
+
+func (l *defaultLogger) Errorw(msg string, keysAndValues ...any) {
+	l.logw(ERROR, msg, keysAndValues...)
+}
+
+func (l *defaultLogger) Warnw(msg string, keysAndValues ...any) {
+	l.logw(WARN, msg, keysAndValues...)
+}
+
+func (l *defaultLogger) Infow(msg string, keysAndValues ...any) {
+	l.logw(INFO, msg, keysAndValues...)
+}
+
+func (l *defaultLogger) Debugw(msg string, keysAndValues ...any) {
+	l.logw(DEBUG, msg, keysAndValues...)
+}
+
+func (l *defaultLogger) Tracew(msg string, keysAndValues ...any) {
+	l.logw(TRACE, msg, keysAndValues...)
+}
+
+func (l *defaultLogger) Log(level Level, args ...any) {
+	l.logf(level, nil, args...)
+}
+
+func (l *defaultLogger) Logf(level Level, format string, args ...any) {
+	l.logf(level, &format, args...)
+}
+
+func (l *defaultLogger) Logw(level Level, msg string, keysAndValues ...any) {
+	l.logw(level, msg, keysAndValues...)
+}
+
+// LogFields logs the message with the fields at the level. Unlike Logw the fields are
+// neither boxed nor converted, with the typed constructors such as Int and Str it does not
+// allocate once the buffers are pooled.
+func (l *defaultLogger) LogFields(level Level, msg string, fields ...Field) {
+	l.logFields(level, msg, fields)
+}
+
+// loggerHolder wraps the default logger so that loggers of different types can be stored
+// in the same atomic.Value.
+type loggerHolder struct {
+	Logger
+}
+
+// logger holds the default logger, it is swapped atomically by SetLogger.
+var logger atomic.Value
+
+func init() {
+	logger.Store(loggerHolder{&defaultLogger{
+		level: newLevel(defaultLevel),
+		core:  newCore(os.Stdout, defaultPrefix, defaultFlags, NewTextEncoder()),
+	}})
+}
+
+// copyArgs returns a copy of the arguments of a package-level function passed to a Logger
+// set by SetLogger. Passing them to an interface method would make the compiler allocate
+// them on the heap on every call, the copy confines this allocation to the other loggers
+// so that the calls of the default logger at a disabled level do not allocate.
+func copyArgs(args []any) []any {
+	return append([]any(nil), args...)
+}
+
+// copyFields is copyArgs for the fields of LogFields.
+func copyFields(fields []Field) []Field {
+	return append([]Field(nil), fields...)
+}
+
+// loadLogger returns the default logger.
+func loadLogger() Logger {
+	return logger.Load().(loggerHolder).Logger
 }
 
 // SetFlags sets the output flags for the standard logger.
 // The flag bits are Ldate, Ltime, and so on.
 func SetFlags(flag int) {
-	logger.SetFlags(flag)
+	loadLogger().SetFlags(flag)
+}
+
+// SetEncoder sets the encoder used by the standard logger to render entries,
+// e.g. NewTextEncoder (default) or NewJSONEncoder.
+func SetEncoder(encoder Encoder) {
+	loadLogger().SetEncoder(encoder)
+}
+
+// SetSampling sets the sampling and rate limiting of the standard logger, nil disables
+// sampling. It has no effect if the logger set by SetLogger does not support sampling.
+func SetSampling(sampling *Sampling) {
+	if l, ok := loadLogger().(interface{ SetSampling(*Sampling) }); ok {
+		l.SetSampling(sampling)
+	}
+}
+
+// AddHook appends hooks to the pipeline of the standard logger, see Hook. It has no effect
+// if the logger set by SetLogger does not support hooks.
+func AddHook(hooks ...Hook) {
+	if l, ok := loadLogger().(interface{ AddHook(...Hook) }); ok {
+		l.AddHook(hooks...)
+	}
+}
+
+// SetHooks replaces the pipeline of the standard logger with hooks, no hooks removes all
+// hooks. It has no effect if the logger set by SetLogger does not support hooks.
+func SetHooks(hooks ...Hook) {
+	if l, ok := loadLogger().(interface{ SetHooks(...Hook) }); ok {
+		l.SetHooks(hooks...)
+	}
+}
+
+// SetTraceback sets how the errors logged by the standard logger are rendered, e.g.
+// &Traceback{StackLevel: ERROR, CauseLevel: WARN} renders the stack trace of errors on ERROR
+// and FATAL and their causes from WARN. nil (default) disables it. It has no effect if the
+// logger set by SetLogger does not support it.
+func SetTraceback(traceback *Traceback) {
+	if l, ok := loadLogger().(interface{ SetTraceback(*Traceback) }); ok {
+		l.SetTraceback(traceback)
+	}
 }
 
 // SetPrefix sets the output prefix for the standard logger.
 func SetPrefix(prefix string) {
-	logger.SetPrefix(prefix)
+	loadLogger().SetPrefix(prefix)
 }
 
 // SetOutput sets the output destination for the standard logger.
 func SetOutput(w io.Writer) {
-	logger.SetOutput(w)
+	loadLogger().SetOutput(w)
 }
 
 // SetLevel sets the level of logs below which logs wid not be output.
 // The default log level is defaultLevel.
-// Note that this method is not concurrent-safe.
+// It is safe to call at any time, concurrently with logging.
 func SetLevel(lv any) {
-	logger.SetLevel(ToLevel(lv))
+	loadLogger().SetLevel(ToLevel(lv))
+}
+
+// GetLevel returns the level of the standard logger, or defaultLevel (WARN) if the logger
+// set by SetLogger does not report its level.
+func GetLevel() Level {
+	if l, ok := loadLogger().(interface{ GetLevel() Level }); ok {
+		return l.GetLevel()
+	}
+	return defaultLevel
+}
+
+// Enabled reports whether the standard logger writes entries of the level, it can guard
+// the computation of expensive arguments:
+//
+//	if log.Enabled(log.DEBUG) {
+//		log.Debugw("state", "dump", expensiveDump())
+//	}
+//
+// It returns true if the logger set by SetLogger does not report it.
+func Enabled(lv Level) bool {
+	if l, ok := loadLogger().(interface{ Enabled(Level) bool }); ok {
+		return l.Enabled(lv)
+	}
+	return true
 }
 
 // DefaultLogger return the default logger for kitex.
 func DefaultLogger() Logger {
-	return logger
+	return loadLogger()
 }
 
 // SetLogger sets the default logger.
-// Note that this method is not concurrent-safe and must not be caded
-// after the use of DefaultLogger and global functions in this package.
+// It is safe to call at any time, the package-level functions called after SetLogger
+// returns use the new logger. Loggers returned by DefaultLogger, With or Named before
+// are not affected.
 func SetLogger(l Logger) {
-	logger = l
+	logger.Store(loggerHolder{l})
 }
 
-// Fatal cads the default logger's Fatal method and then os.Exit(1).
+// Fatal cads the default logger's Fatal method and then exits, see SetFatalPolicy.
 func Fatal(args ...any) {
-	logger.Fatal(args...)
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logf(FATAL, nil, args...)
+		return
+	}
+	l.Fatal(copyArgs(args)...)
 }
 
 // Error cads the default logger's Error method.
 func Error(args ...any) {
-	logger.Error(args...)
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logf(ERROR, nil, args...)
+		return
+	}
+	l.Error(copyArgs(args)...)
 }
 
 // Warn cads the default logger's Warn method.
 func Warn(args ...any) {
-	logger.Warn(args...)
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logf(WARN, nil, args...)
+		return
+	}
+	l.Warn(copyArgs(args)...)
 }
 
 // Info cads the default logger's Info method.
 func Info(args ...any) {
-	logger.Info(args...)
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logf(INFO, nil, args...)
+		return
+	}
+	l.Info(copyArgs(args)...)
 }
 
 // Debug cads the default logger's Debug method.
 func Debug(args ...any) {
-	logger.Debug(args...)
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logf(DEBUG, nil, args...)
+		return
+	}
+	l.Debug(copyArgs(args)...)
 }
 
 // Trace cads the default logger's Trace method.
 func Trace(args ...any) {
-	logger.Trace(args...)
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logf(TRACE, nil, args...)
+		return
+	}
+	l.Trace(copyArgs(args)...)
 }
 
-// Fatalf cads the default logger's Fatalf method and then os.Exit(1).
+// Fatalf cads the default logger's Fatalf method and then exits, see SetFatalPolicy.
 func Fatalf(format string, args ...any) {
-	logger.Fatalf(format, args...)
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logf(FATAL, &format, args...)
+		return
+	}
+	l.Fatalf(format, copyArgs(args)...)
 }
 
 // Errorf cads the default logger's Errorf method.
 func Errorf(format string, args ...any) {
-	logger.Errorf(format, args...)
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logf(ERROR, &format, args...)
+		return
+	}
+	l.Errorf(format, copyArgs(args)...)
 }
 
 // Warnf cads the default logger's Warnf method.
 func Warnf(format string, args ...any) {
-	logger.Warnf(format, args...)
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logf(WARN, &format, args...)
+		return
+	}
+	l.Warnf(format, copyArgs(args)...)
 }
 
 // Infof cads the default logger's Infof method.
 func Infof(format string, args ...any) {
-	logger.Infof(format, args...)
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logf(INFO, &format, args...)
+		return
+	}
+	l.Infof(format, copyArgs(args)...)
 }
 
 // Debugf cads the default logger's Debugf method.
 func Debugf(format string, args ...any) {
-	logger.Debugf(format, args...)
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logf(DEBUG, &format, args...)
+		return
+	}
+	l.Debugf(format, copyArgs(args)...)
 }
 
 // Tracef cads the default logger's Tracef method.
 func Tracef(format string, args ...any) {
-	logger.Tracef(format, args...)
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logf(TRACE, &format, args...)
+		return
+	}
+	l.Tracef(format, copyArgs(args)...)
+}
+
+// Fatalw cads the default logger's Fatalw method and then exits, see SetFatalPolicy.
+func Fatalw(msg string, keysAndValues ...any) {
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logw(FATAL, msg, keysAndValues...)
+		return
+	}
+	l.Fatalw(msg, copyArgs(keysAndValues)...)
+}
+
+// Errorw cads the default logger's Errorw method.
+func Errorw(msg string, keysAndValues ...any) {
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logw(ERROR, msg, keysAndValues...)
+		return
+	}
+	l.Errorw(msg, copyArgs(keysAndValues)...)
+}
+
+// Warnw cads the default logger's Warnw method.
+func Warnw(msg string, keysAndValues ...any) {
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logw(WARN, msg, keysAndValues...)
+		return
+	}
+	l.Warnw(msg, copyArgs(keysAndValues)...)
+}
+
+// Infow cads the default logger's Infow method.
+func Infow(msg string, keysAndValues ...any) {
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logw(INFO, msg, keysAndValues...)
+		return
+	}
+	l.Infow(msg, copyArgs(keysAndValues)...)
+}
+
+// Debugw cads the default logger's Debugw method.
+func Debugw(msg string, keysAndValues ...any) {
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logw(DEBUG, msg, keysAndValues...)
+		return
+	}
+	l.Debugw(msg, copyArgs(keysAndValues)...)
+}
+
+// Tracew cads the default logger's Tracew method.
+func Tracew(msg string, keysAndValues ...any) {
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logw(TRACE, msg, keysAndValues...)
+		return
+	}
+	l.Tracew(msg, copyArgs(keysAndValues)...)
+}
+
+// Log cads the default logger's Log method, it logs at any level including the levels
+// registered by RegisterLevel.
+func Log(level Level, args ...any) {
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logf(level, nil, args...)
+		return
+	}
+	l.Log(level, copyArgs(args)...)
+}
+
+// Logf cads the default logger's Logf method.
+func Logf(level Level, format string, args ...any) {
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logf(level, &format, args...)
+		return
+	}
+	l.Logf(level, format, copyArgs(args)...)
+}
+
+// Logw cads the default logger's Logw method.
+func Logw(level Level, msg string, keysAndValues ...any) {
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logw(level, msg, keysAndValues...)
+		return
+	}
+	l.Logw(level, msg, copyArgs(keysAndValues)...)
+}
+
+// LogFields cads the default logger's LogFields method, or its Logw method if the logger
+// set by SetLogger does not implement LogFields.
+func LogFields(level Level, msg string, fields ...Field) {
+	l := loadLogger()
+	if std, ok := l.(*defaultLogger); ok {
+		std.logFields(level, msg, fields)
+		return
+	}
+	if lf, ok := l.(interface {
+		LogFields(Level, string, ...Field)
+	}); ok {
+		lf.LogFields(level, msg, copyFields(fields)...)
+		return
+	}
+	values := make([]any, len(fields))
+	for index := range fields {
+		values[index] = fields[index]
+	}
+	l.Logw(level, msg, values...)
+}
+
+// WithCallerSkip returns a child of the default logger skipping n additional frames to find
+// the caller, or the default logger itself if it does not support it. The package-level
+// functions and the methods of the child report the same caller.
+func WithCallerSkip(n int) Logger {
+	l := loadLogger()
+	if skipper, ok := l.(interface{ WithCallerSkip(int) Logger }); ok {
+		return skipper.WithCallerSkip(n)
+	}
+	return l
+}
+
+// Named returns a child of the default logger with the given name, see SetLevels.
+func Named(name string) Logger {
+	return loadLogger().Named(name)
+}
+
+// With returns a child of the default logger that renders the given key/value pairs
+// on every line.
+func With(keysAndValues ...any) Logger {
+	return loadLogger().With(keysAndValues...)
 }
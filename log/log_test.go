@@ -2,9 +2,21 @@ package log
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/stkali/utility/log/internal/callertest"
 )
 
 func TestToLevel(t *testing.T) {
@@ -358,9 +370,329 @@ func checkOutput(t *testing.T, level Level, args []any, expectArgs string, forma
 	}
 }
 
+func TestStructured(t *testing.T) {
+	recorder := new(bytes.Buffer)
+	SetOutput(recorder)
+	SetLevel(INFO)
+	SetFlags(0)
+	SetPrefix("")
+
+	t.Run("key and values", func(t *testing.T) {
+		recorder.Reset()
+		Infow("user login", "user", "alice", "id", 42, "ok", true)
+		require.Equal(t, INFO.String()+"user login user=alice id=42 ok=true\n", recorder.String())
+
+		recorder.Reset()
+		Debugw("ignored", "key", "value")
+		require.Equal(t, "", recorder.String())
+	})
+
+	t.Run("with fields", func(t *testing.T) {
+		child := With("request", "r-1")
+		grandchild := child.With("step", 2)
+
+		recorder.Reset()
+		child.Warn("slow request")
+		require.Equal(t, WARN.String()+"slow request request=r-1\n", recorder.String())
+
+		recorder.Reset()
+		child.Errorf("failed after %d retries", 3)
+		require.Equal(t, ERROR.String()+"failed after 3 retries request=r-1\n", recorder.String())
+
+		recorder.Reset()
+		grandchild.Infow("done", "cost", "1 s")
+		require.Equal(t, INFO.String()+`done request=r-1 step=2 cost="1 s"`+"\n", recorder.String())
+
+		// the parent is not affected by the child
+		recorder.Reset()
+		Info("plain")
+		require.Equal(t, INFO.String()+"plain\n", recorder.String())
+	})
+
+	t.Run("all levels", func(t *testing.T) {
+		SetLevel(TRACE)
+		defer SetLevel(INFO)
+		for lv, fn := range []func(string, ...any){Tracew, Debugw, Infow, Warnw, Errorw, Fatalw} {
+			recorder.Reset()
+			fn("msg", "k", "v")
+			require.Equal(t, Level(lv).String()+"msg k=v\n", recorder.String())
+		}
+	})
+}
+
+func TestLogFields(t *testing.T) {
+	recorder := new(bytes.Buffer)
+	SetOutput(recorder)
+	SetLevel(INFO)
+	SetFlags(Lshortfile)
+	defer SetFlags(defaultFlags)
+
+	LogFields(INFO, "done", Int("n", 3), Str("user", "alice"), Dur("cost", time.Second), Int("line", callertest.Line()))
+	got := recorder.String()
+	line := strings.TrimSuffix(got[strings.LastIndex(got, "=")+1:], "\n")
+	require.Equal(t, "log_test.go:"+line+": "+INFO.String()+"done n=3 user=alice cost=1s line="+line+"\n", got)
+
+	recorder.Reset()
+	LogFields(DEBUG, "ignored", Int("n", 3))
+	require.Equal(t, "", recorder.String())
+
+	// the fields of the pooled entry are not shared with the next call
+	SetFlags(0)
+	child := With("request", "r-1").(*defaultLogger)
+	child.LogFields(WARN, "first", Int("a", 1), Int("b", 2))
+	child.LogFields(WARN, "second", Int("c", 3))
+	require.Equal(t, WARN.String()+"first request=r-1 a=1 b=2\n"+WARN.String()+"second request=r-1 c=3\n", recorder.String())
+
+	// the other loggers receive the fields through Logw
+	defer SetLogger(DefaultLogger())
+	other := &defaultLogger{level: newLevel(INFO), core: newCore(recorder, "", 0, NewTextEncoder())}
+	SetLogger(struct{ Logger }{other})
+	recorder.Reset()
+	LogFields(INFO, "wrapped", Int("n", 1))
+	require.Equal(t, INFO.String()+"wrapped n=1\n", recorder.String())
+}
+
+func TestEnabled(t *testing.T) {
+	SetLevel(INFO)
+	defer SetLevel(defaultLevel)
+	require.True(t, Enabled(INFO))
+	require.True(t, Enabled(ERROR))
+	require.False(t, Enabled(DEBUG))
+
+	require.NoError(t, SetLevels("storage=debug"))
+	defer func() { _ = SetLevels("") }()
+	named := Named("storage").(*defaultLogger)
+	require.True(t, named.Enabled(DEBUG))
+	require.False(t, named.Enabled(TRACE))
+
+	defer SetLogger(DefaultLogger())
+	SetLogger(struct{ Logger }{named})
+	require.True(t, Enabled(TRACE))
+}
+
+// callerPattern matches a line logged by TestCaller with the line of the call as `line`.
+var callerPattern = regexp.MustCompile(`^log_test\.go:(\d+) log\.TestCaller: .*line=(\d+)`)
+
+func TestCaller(t *testing.T) {
+	recorder := new(bytes.Buffer)
+	SetOutput(recorder)
+	SetLevel(INFO)
+	SetFlags(Lshortfile | Lfuncname)
+	defer SetFlags(defaultFlags)
+	ctx := NewContext(context.Background(), "request", "r-1")
+	requireCallers := func(n int) {
+		t.Helper()
+		lines := strings.Split(strings.TrimSpace(recorder.String()), "\n")
+		require.Len(t, lines, n)
+		for _, got := range lines {
+			groups := callerPattern.FindStringSubmatch(got)
+			require.NotNil(t, groups, got)
+			require.Equal(t, groups[2], groups[1], got)
+		}
+		recorder.Reset()
+	}
+
+	// the package-level functions, the methods and the children report the same caller
+	Warn("line=", callertest.Line())
+	DefaultLogger().Warn("line=", callertest.Line())
+	Infof("line=%d", callertest.Line())
+	DefaultLogger().Infow("d", "line", callertest.Line())
+	Logw(WARN, "e", "line", callertest.Line())
+	InfoContext(ctx, "line=", callertest.Line())
+	DefaultLogger().WithContext(ctx).Info("line=", callertest.Line())
+	Named("storage").Warn("line=", callertest.Line())
+	LogFields(WARN, "i", Int("line", callertest.Line()))
+	With("k", "v").(*defaultLogger).LogFields(WARN, "j", Int("line", callertest.Line()))
+	requireCallers(10)
+
+	// the helpers report the line calling them
+	defer SetLogger(DefaultLogger())
+	SetLogger(WithCallerSkip(1))
+	callertest.Helper(Warn, "line=", callertest.Line())
+	requireCallers(1)
+	func(line int) {
+		DefaultLogger().Named("storage").With("k", "v").Warn("line=", line)
+	}(callertest.Line())
+	requireCallers(1)
+
+	// unsupported by other loggers
+	SetLogger(struct{ Logger }{DefaultLogger()})
+	require.Equal(t, DefaultLogger(), WithCallerSkip(1))
+}
+
+func TestDisabledAllocs(t *testing.T) {
+	SetLevel(INFO)
+	defer SetLevel(defaultLevel)
+	err := errors.New("boom")
+	allocs := testing.AllocsPerRun(100, func() {
+		Debug("request handled")
+		Debugf("request %s handled", "r-1")
+		Debugw("request handled", "request", "r-1", "err", err)
+		LogFields(DEBUG, "request handled", Str("request", "r-1"), Int("status", 500), Dur("cost", time.Second))
+	})
+	require.Equal(t, float64(0), allocs)
+}
+
 func TestConfig(t *testing.T) {
-	require.Equal(t, logger, DefaultLogger())
+	require.Equal(t, loadLogger(), DefaultLogger())
+	defer SetLogger(DefaultLogger())
 	newLog := new(defaultLogger)
 	SetLogger(newLog)
 	require.Equal(t, newLog, DefaultLogger())
 }
+
+// countWriter counts the lines written to it, it is safe for concurrent use.
+type countWriter struct {
+	lines int64
+}
+
+func (w *countWriter) Write(b []byte) (int, error) {
+	atomic.AddInt64(&w.lines, int64(bytes.Count(b, []byte{'\n'})))
+	return len(b), nil
+}
+
+func TestConcurrentConfig(t *testing.T) {
+	origin := DefaultLogger()
+	defer func() {
+		SetLogger(origin)
+		SetOutput(os.Stdout)
+		SetEncoder(nil)
+		_ = SetLevels("")
+	}()
+
+	writer := &countWriter{}
+	SetOutput(writer)
+	SetLevel(TRACE)
+	other := &defaultLogger{level: newLevel(defaultLevel), core: newCore(writer, "", 0, NewTextEncoder())}
+	named := Named("storage")
+
+	const loggers, lines = 16, 200
+	wg := sync.WaitGroup{}
+	done := make(chan struct{})
+	for i := 0; i < loggers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < lines; j++ {
+				Infof("line %d", j)
+				Infow("line", "n", j)
+				named.Debug("line")
+				DefaultLogger().Warn("line")
+				// ERROR is enabled by all the levels set below
+				Error("line")
+			}
+		}()
+	}
+
+	configured := make(chan struct{})
+	go func() {
+		defer close(configured)
+		levels := []Level{TRACE, DEBUG, INFO, WARN, ERROR}
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			SetLevel(levels[i%len(levels)])
+			SetPrefix(strconv.Itoa(i))
+			SetFlags(i % (Lmsgprefix << 1))
+			SetOutput(writer)
+			SetEncoder(NewJSONEncoder())
+			_ = SetLevels("storage=" + levels[i%len(levels)].Name())
+			if i%2 == 0 {
+				SetLogger(other)
+			} else {
+				SetLogger(origin)
+			}
+		}
+	}()
+	wg.Wait()
+	close(done)
+	<-configured
+	require.True(t, atomic.LoadInt64(&writer.lines) > 0)
+}
+
+// benchmarkLogger sets a standard logger writing to io.Discard at INFO with the default
+// flags for the duration of the benchmark.
+func benchmarkLogger(b *testing.B, encoder Encoder) {
+	origin := DefaultLogger()
+	SetLogger(&defaultLogger{
+		level: newLevel(INFO),
+		core:  newCore(io.Discard, defaultPrefix, defaultFlags, encoder),
+	})
+	b.Cleanup(func() {
+		SetLogger(origin)
+	})
+	b.ReportAllocs()
+	b.ResetTimer()
+}
+
+func BenchmarkDisabled(b *testing.B) {
+	err := errors.New("boom")
+	b.Run("Debug", func(b *testing.B) {
+		benchmarkLogger(b, NewTextEncoder())
+		for i := 0; i < b.N; i++ {
+			Debug("request handled")
+		}
+	})
+	b.Run("Debugf", func(b *testing.B) {
+		benchmarkLogger(b, NewTextEncoder())
+		for i := 0; i < b.N; i++ {
+			Debugf("request %s handled in %s", "r-1", "1ms")
+		}
+	})
+	b.Run("Debugw", func(b *testing.B) {
+		benchmarkLogger(b, NewTextEncoder())
+		for i := 0; i < b.N; i++ {
+			Debugw("request handled", "request", "r-1", "err", err)
+		}
+	})
+	b.Run("LogFields", func(b *testing.B) {
+		benchmarkLogger(b, NewTextEncoder())
+		for i := 0; i < b.N; i++ {
+			LogFields(DEBUG, "request handled", Str("request", "r-1"), Int("status", 200+i), Dur("cost", time.Duration(i)))
+		}
+	})
+}
+
+func BenchmarkEnabled(b *testing.B) {
+	b.Run("Info", func(b *testing.B) {
+		benchmarkLogger(b, NewTextEncoder())
+		for i := 0; i < b.N; i++ {
+			Info("request handled")
+		}
+	})
+	b.Run("Infof", func(b *testing.B) {
+		benchmarkLogger(b, NewTextEncoder())
+		for i := 0; i < b.N; i++ {
+			Infof("request %s handled in %s", "r-1", "1ms")
+		}
+	})
+	b.Run("Infow", func(b *testing.B) {
+		benchmarkLogger(b, NewTextEncoder())
+		for i := 0; i < b.N; i++ {
+			Infow("request handled", "request", "r-1", "status", 200)
+		}
+	})
+	b.Run("LogFields", func(b *testing.B) {
+		benchmarkLogger(b, NewTextEncoder())
+		for i := 0; i < b.N; i++ {
+			LogFields(INFO, "request handled", Str("request", "r-1"), Int("status", 200+i), Dur("cost", time.Duration(i)))
+		}
+	})
+	b.Run("LogFieldsJSON", func(b *testing.B) {
+		benchmarkLogger(b, NewJSONEncoder())
+		for i := 0; i < b.N; i++ {
+			LogFields(INFO, "request handled", Str("request", "r-1"), Int("status", 200+i), Dur("cost", time.Duration(i)))
+		}
+	})
+	b.Run("Parallel", func(b *testing.B) {
+		benchmarkLogger(b, NewTextEncoder())
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				LogFields(INFO, "request handled", Str("request", "r-1"), Int("status", 200+i))
+			}
+		})
+	})
+}
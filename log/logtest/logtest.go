@@ -0,0 +1,427 @@
+// Copyright 2021-2024 The utility Authors. All rights reserved.
+// Use of this source code is governed by the MIT license that can be found in the
+// LICENSE file
+
+// Package logtest provides a log.Logger that records the entries in memory so that tests
+// can assert on their level, message and fields regardless of the flags and encoder.
+//
+//	func TestLogin(t *testing.T) {
+//		recorder := logtest.NewRecorder()
+//		defer recorder.Install()()
+//
+//		login("alice")
+//		recorder.AssertLogged(t, log.WARN, "wrong password")
+//	}
+package logtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stkali/utility/log"
+)
+
+// logPackage is the prefix of the functions of the log package, they are skipped when
+// resolving the caller so that the package-level functions report their own caller.
+const logPackage = "github.com/stkali/utility/log."
+
+// Record is an entry captured by a Recorder.
+type Record struct {
+	Time  time.Time
+	Level log.Level
+	// Name is the name of the logger given by Named.
+	Name    string
+	Message string
+	// Fields are the fields bound by With and WithContext followed by the fields of the call.
+	Fields []log.Field
+	Caller log.Caller
+}
+
+// String returns the record as `LEVEL name: message key=value`.
+func (r Record) String() string {
+	var sb strings.Builder
+	sb.WriteString(r.Level.Name())
+	sb.WriteByte(' ')
+	sb.WriteString(r.text())
+	return sb.String()
+}
+
+// text returns the logger name, the message and the fields of the record.
+func (r Record) text() string {
+	var sb strings.Builder
+	if r.Name != "" {
+		sb.WriteString(r.Name)
+		sb.WriteString(": ")
+	}
+	sb.WriteString(r.Message)
+	for _, field := range r.Fields {
+		sb.WriteByte(' ')
+		sb.WriteString(field.String())
+	}
+	return sb.String()
+}
+
+// Field returns the value of the last field with the key and whether it exists.
+func (r Record) Field(key string) (any, bool) {
+	for index := len(r.Fields) - 1; index >= 0; index-- {
+		if r.Fields[index].Key == key {
+			return r.Fields[index].Any(), true
+		}
+	}
+	return nil, false
+}
+
+// recording holds the records shared by a recorder and its children.
+type recording struct {
+	mtx     sync.Mutex
+	records []Record
+}
+
+// Recorder is a log.Logger that records the entries in memory, the children created by
+// With, WithContext and Named share the records and the level of their parent. It is safe
+// for concurrent use.
+//
+// The output settings (SetOutput, SetPrefix, SetFlags, SetEncoder) are ignored. FATAL
+// entries are recorded then handled by log.FatalExit, tests logging FATAL should set
+// log.SetFatalPolicy(log.FatalPolicy{Panic: true}).
+type Recorder struct {
+	recording *recording
+	// level is the level threshold of the recorder, it is accessed atomically and shared
+	// with the children created by With, WithContext, Named and WithCallerSkip.
+	level  *int32
+	name   string
+	fields []log.Field
+	// callerSkip is the number of additional frames skipped to find the caller, see
+	// WithCallerSkip.
+	callerSkip int
+}
+
+var _ log.Logger = (*Recorder)(nil)
+
+// NewRecorder returns a Recorder recording the entries of all levels.
+func NewRecorder() *Recorder {
+	level := int32(log.TRACE)
+	return &Recorder{recording: &recording{}, level: &level}
+}
+
+// Install sets the recorder as the standard logger and returns the function restoring the
+// previous one, in the style of lib.Replace:
+//
+//	defer recorder.Install()()
+func (r *Recorder) Install() (restore func()) {
+	previous := log.DefaultLogger()
+	log.SetLogger(r)
+	return func() {
+		log.SetLogger(previous)
+	}
+}
+
+// Entries returns a copy of the recorded entries in the order they were logged.
+func (r *Recorder) Entries() []Record {
+	r.recording.mtx.Lock()
+	defer r.recording.mtx.Unlock()
+	records := make([]Record, len(r.recording.records))
+	copy(records, r.recording.records)
+	return records
+}
+
+// Filter returns the recorded entries of the level.
+func (r *Recorder) Filter(level log.Level) []Record {
+	var records []Record
+	for _, record := range r.Entries() {
+		if record.Level == level {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// Reset removes the recorded entries.
+func (r *Recorder) Reset() {
+	r.recording.mtx.Lock()
+	defer r.recording.mtx.Unlock()
+	r.recording.records = nil
+}
+
+// Logged reports whether an entry of the level whose name, message or fields contain
+// substr has been recorded.
+func (r *Recorder) Logged(level log.Level, substr string) bool {
+	for _, record := range r.Entries() {
+		if record.Level == level && strings.Contains(record.text(), substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// AssertLogged reports a test error and returns false unless an entry of the level whose
+// name, message or fields contain substr has been recorded.
+func (r *Recorder) AssertLogged(t testing.TB, level log.Level, substr string) bool {
+	t.Helper()
+	if r.Logged(level, substr) {
+		return true
+	}
+	t.Errorf("no %s entry containing %q has been logged, entries:\n%s", level.Name(), substr, r.dump())
+	return false
+}
+
+// AssertNotLogged reports a test error and returns false if an entry of the level whose
+// name, message or fields contain substr has been recorded.
+func (r *Recorder) AssertNotLogged(t testing.TB, level log.Level, substr string) bool {
+	t.Helper()
+	if !r.Logged(level, substr) {
+		return true
+	}
+	t.Errorf("unexpected %s entry containing %q has been logged, entries:\n%s", level.Name(), substr, r.dump())
+	return false
+}
+
+// dump returns the recorded entries one per line.
+func (r *Recorder) dump() string {
+	var sb strings.Builder
+	for _, record := range r.Entries() {
+		sb.WriteString("    ")
+		sb.WriteString(record.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// record appends an entry to the records, FATAL entries are then handled by log.FatalExit.
+func (r *Recorder) record(lv log.Level, msg string, fields []log.Field) {
+	record := Record{
+		Time:    time.Now(),
+		Level:   lv,
+		Name:    r.name,
+		Message: msg,
+		Fields:  fields,
+		Caller:  caller(r.callerSkip),
+	}
+	r.recording.mtx.Lock()
+	r.recording.records = append(r.recording.records, record)
+	r.recording.mtx.Unlock()
+	if lv == log.FATAL {
+		log.FatalExit(msg)
+	}
+}
+
+// caller returns the caller of the Recorder method, skipping the package-level functions
+// of the log package and skip additional frames.
+func caller(skip int) log.Caller {
+	var pcs [16]uintptr
+	// skip runtime.Callers, caller, record, logf/logw/logFields and the Recorder method
+	n := runtime.Callers(5, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		switch {
+		case strings.HasPrefix(frame.Function, logPackage):
+			// a package-level function of the log package
+		case skip > 0:
+			skip--
+		default:
+			return log.Caller{File: frame.File, Line: frame.Line, Function: frame.Function}
+		}
+		if !more {
+			return log.Caller{}
+		}
+	}
+}
+
+// Enabled reports whether the recorder records entries of the level, the rules set by
+// log.SetLevels override the level of the named recorders like for the standard logger.
+func (r *Recorder) Enabled(lv log.Level) bool {
+	threshold, ok := log.LookupLevel(r.name)
+	if !ok {
+		threshold = r.GetLevel()
+	}
+	return lv.Severity() >= threshold.Severity()
+}
+
+func (r *Recorder) logf(lv log.Level, format *string, args ...any) {
+	if !r.Enabled(lv) {
+		return
+	}
+	var msg string
+	if format != nil {
+		msg = fmt.Sprintf(*format, args...)
+	} else {
+		msg = fmt.Sprint(args...)
+	}
+	r.record(lv, msg, r.fields)
+}
+
+func (r *Recorder) logw(lv log.Level, msg string, keysAndValues ...any) {
+	if !r.Enabled(lv) {
+		return
+	}
+	fields := log.Fields(keysAndValues...)
+	if len(r.fields) > 0 {
+		fields = append(append(make([]log.Field, 0, len(r.fields)+len(fields)), r.fields...), fields...)
+	}
+	r.record(lv, msg, fields)
+}
+
+func (r *Recorder) logFields(lv log.Level, msg string, fields []log.Field) {
+	if !r.Enabled(lv) {
+		return
+	}
+	r.record(lv, msg, append(append(make([]log.Field, 0, len(r.fields)+len(fields)), r.fields...), fields...))
+}
+
+// child returns a recorder sharing the records with the fields appended and the name.
+func (r *Recorder) child(name string, fields []log.Field) *Recorder {
+	return &Recorder{
+		recording:  r.recording,
+		level:      r.level,
+		name:       name,
+		fields:     append(append(make([]log.Field, 0, len(r.fields)+len(fields)), r.fields...), fields...),
+		callerSkip: r.callerSkip,
+	}
+}
+
+// With implements the log.Logger interface.
+func (r *Recorder) With(keysAndValues ...any) log.Logger {
+	return r.child(r.name, log.Fields(keysAndValues...))
+}
+
+// WithContext implements the log.Logger interface, the fields of ctx are bound like With.
+func (r *Recorder) WithContext(ctx context.Context) log.Logger {
+	fields := log.ContextFields(ctx)
+	if len(fields) == 0 {
+		return r
+	}
+	return r.child(r.name, fields)
+}
+
+// Named implements the log.Logger interface, the names are joined by dots.
+func (r *Recorder) Named(name string) log.Logger {
+	if name == "" {
+		return r
+	}
+	if r.name != "" {
+		name = r.name + "." + name
+	}
+	return r.child(name, nil)
+}
+
+// WithCallerSkip returns a child recorder that skips n additional frames to find the
+// caller of its entries, see log.WithCallerSkip.
+func (r *Recorder) WithCallerSkip(n int) log.Logger {
+	child := r.child(r.name, nil)
+	child.callerSkip += n
+	return child
+}
+
+// SetLevel implements the log.Logger interface.
+func (r *Recorder) SetLevel(lv log.Level) {
+	atomic.StoreInt32(r.level, int32(lv))
+}
+
+// GetLevel returns the level set by SetLevel.
+func (r *Recorder) GetLevel() log.Level {
+	return log.Level(atomic.LoadInt32(r.level))
+}
+
+func (r *Recorder) SetOutput(io.Writer) {}
+
+func (r *Recorder) SetPrefix(string) {}
+
+func (r *Recorder) SetFlags(int) {}
+
+func (r *Recorder) SetEncoder(log.Encoder) {}
+
+func (r *Recorder) Fatal(args ...any) {
+	r.logf(log.FATAL, nil, args...)
+}
+
+func (r *Recorder) Error(args ...any) {
+	r.logf(log.ERROR, nil, args...)
+}
+
+func (r *Recorder) Warn(args ...any) {
+	r.logf(log.WARN, nil, args...)
+}
+
+func (r *Recorder) Info(args ...any) {
+	r.logf(log.INFO, nil, args...)
+}
+
+func (r *Recorder) Debug(args ...any) {
+	r.logf(log.DEBUG, nil, args...)
+}
+
+func (r *Recorder) Trace(args ...any) {
+	r.logf(log.TRACE, nil, args...)
+}
+
+func (r *Recorder) Fatalf(format string, args ...any) {
+	r.logf(log.FATAL, &format, args...)
+}
+
+func (r *Recorder) Errorf(format string, args ...any) {
+	r.logf(log.ERROR, &format, args...)
+}
+
+func (r *Recorder) Warnf(format string, args ...any) {
+	r.logf(log.WARN, &format, args...)
+}
+
+func (r *Recorder) Infof(format string, args ...any) {
+	r.logf(log.INFO, &format, args...)
+}
+
+func (r *Recorder) Debugf(format string, args ...any) {
+	r.logf(log.DEBUG, &format, args...)
+}
+
+func (r *Recorder) Tracef(format string, args ...any) {
+	r.logf(log.TRACE, &format, args...)
+}
+
+func (r *Recorder) Fatalw(msg string, keysAndValues ...any) {
+	r.logw(log.FATAL, msg, keysAndValues...)
+}
+
+func (r *Recorder) Errorw(msg string, keysAndValues ...any) {
+	r.logw(log.ERROR, msg, keysAndValues...)
+}
+
+func (r *Recorder) Warnw(msg string, keysAndValues ...any) {
+	r.logw(log.WARN, msg, keysAndValues...)
+}
+
+func (r *Recorder) Infow(msg string, keysAndValues ...any) {
+	r.logw(log.INFO, msg, keysAndValues...)
+}
+
+func (r *Recorder) Debugw(msg string, keysAndValues ...any) {
+	r.logw(log.DEBUG, msg, keysAndValues...)
+}
+
+func (r *Recorder) Tracew(msg string, keysAndValues ...any) {
+	r.logw(log.TRACE, msg, keysAndValues...)
+}
+
+func (r *Recorder) Log(level log.Level, args ...any) {
+	r.logf(level, nil, args...)
+}
+
+func (r *Recorder) Logf(level log.Level, format string, args ...any) {
+	r.logf(level, &format, args...)
+}
+
+func (r *Recorder) Logw(level log.Level, msg string, keysAndValues ...any) {
+	r.logw(level, msg, keysAndValues...)
+}
+
+func (r *Recorder) LogFields(level log.Level, msg string, fields ...log.Field) {
+	r.logFields(level, msg, fields)
+}
@@ -0,0 +1,188 @@
+package logtest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/stkali/utility/log"
+	"github.com/stkali/utility/log/internal/callertest"
+)
+
+// fakeT records the errors reported by the assertions.
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRecorder(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.Infow("login", "user", "alice")
+	recorder.With("request", "r-1").Named("auth").Warnf("wrong password %d", 3)
+	recorder.Named("db").Error("timeout")
+	recorder.With("request", "r-2").(*Recorder).LogFields(log.INFO, "typed", log.Int("n", 3), log.Str("user", "bob"))
+
+	entries := recorder.Entries()
+	require.Len(t, entries, 4)
+	require.Equal(t, log.INFO, entries[0].Level)
+	require.Equal(t, "login", entries[0].Message)
+	require.Equal(t, []log.Field{{Key: "user", Value: "alice"}}, entries[0].Fields)
+	require.True(t, strings.HasSuffix(entries[0].Caller.File, "logtest_test.go"))
+	require.True(t, strings.HasSuffix(entries[0].Caller.Function, "TestRecorder"))
+	require.False(t, entries[0].Time.IsZero())
+
+	require.Equal(t, "auth", entries[1].Name)
+	value, ok := entries[1].Field("request")
+	require.True(t, ok)
+	require.Equal(t, "r-1", value)
+	_, ok = entries[1].Field("user")
+	require.False(t, ok)
+	require.Equal(t, "WARN auth: wrong password 3 request=r-1", entries[1].String())
+
+	require.Len(t, recorder.Filter(log.ERROR), 1)
+	require.True(t, recorder.Logged(log.WARN, "password"))
+	require.True(t, recorder.Logged(log.WARN, "request=r-1"))
+	require.False(t, recorder.Logged(log.INFO, "password"))
+
+	value, ok = entries[3].Field("n")
+	require.True(t, ok)
+	require.Equal(t, 3, value)
+	require.Equal(t, "INFO typed request=r-2 n=3 user=bob", entries[3].String())
+	require.True(t, strings.HasSuffix(entries[3].Caller.Function, "TestRecorder"))
+
+	recorder.Reset()
+	require.Empty(t, recorder.Entries())
+}
+
+func TestRecorderLevel(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.SetLevel(log.WARN)
+	child := recorder.With("k", "v")
+	recorder.Info("hidden")
+	child.Debugw("hidden")
+	recorder.Log(log.ERROR, "shown")
+	child.Logf(log.WARN, "shown %s", "too")
+	recorder.LogFields(log.INFO, "hidden", log.Int("n", 1))
+	require.Len(t, recorder.Entries(), 2)
+	require.Equal(t, log.WARN, recorder.GetLevel())
+	require.False(t, recorder.Enabled(log.INFO))
+	require.True(t, child.(*Recorder).Enabled(log.ERROR))
+
+	// the children share the level of their parent
+	named := recorder.Named("later")
+	recorder.SetLevel(log.DEBUG)
+	require.True(t, named.(*Recorder).Enabled(log.DEBUG))
+	require.True(t, child.(*Recorder).Enabled(log.DEBUG))
+	named.SetLevel(log.WARN)
+	require.Equal(t, log.WARN, recorder.GetLevel())
+
+	// the rules of the named loggers apply
+	require.NoError(t, log.SetLevels("storage=debug,net=error"))
+	defer func() { _ = log.SetLevels("") }()
+	recorder.Reset()
+	storage := recorder.Named("storage").Named("db")
+	require.True(t, storage.(*Recorder).Enabled(log.DEBUG))
+	storage.Debug("query")
+	recorder.Named("net").Warn("hidden")
+	recorder.Warn("shown")
+	require.Len(t, recorder.Entries(), 2)
+	recorder.AssertLogged(t, log.DEBUG, "storage.db: query")
+	recorder.AssertNotLogged(t, log.WARN, "hidden")
+}
+
+func TestAssertLogged(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.Warn("disk almost full")
+
+	ft := &fakeT{}
+	require.True(t, recorder.AssertLogged(ft, log.WARN, "almost full"))
+	require.True(t, recorder.AssertNotLogged(ft, log.ERROR, "almost full"))
+	require.Empty(t, ft.errors)
+
+	require.False(t, recorder.AssertLogged(ft, log.ERROR, "almost full"))
+	require.False(t, recorder.AssertNotLogged(ft, log.WARN, "disk"))
+	require.Len(t, ft.errors, 2)
+	require.Contains(t, ft.errors[0], `no ERROR entry containing "almost full"`)
+	require.Contains(t, ft.errors[0], "WARN disk almost full")
+	require.Contains(t, ft.errors[1], `unexpected WARN entry containing "disk"`)
+}
+
+func TestInstall(t *testing.T) {
+	origin := log.DefaultLogger()
+	recorder := NewRecorder()
+	restore := recorder.Install()
+	require.Equal(t, recorder, log.DefaultLogger())
+
+	log.Infow("installed", "n", 1)
+	log.InfoContext(log.NewContext(context.Background(), "trace", "t-1"), "with context")
+	log.Named("storage").Debug("named")
+	entries := recorder.Entries()
+	require.Len(t, entries, 3)
+	for _, entry := range entries {
+		require.True(t, strings.HasSuffix(entry.Caller.File, "logtest_test.go"), entry.Caller)
+	}
+	recorder.AssertLogged(t, log.INFO, "trace=t-1")
+	recorder.AssertLogged(t, log.DEBUG, "storage: named")
+
+	restore()
+	require.Equal(t, origin, log.DefaultLogger())
+}
+
+// methodHelper wraps the methods of the standard logger.
+func methodHelper(args ...any) {
+	log.DefaultLogger().Named("storage").Warn(args...)
+}
+
+func TestCallerSkip(t *testing.T) {
+	recorder := NewRecorder()
+	defer recorder.WithCallerSkip(1).(*Recorder).Install()()
+	callertest.Helper(log.Warn, "line=", callertest.Line())
+	methodHelper("line=", callertest.Line())
+	// the line calling the closure
+	func(line int) {
+		log.Warn("line=", line)
+	}(callertest.Line())
+	entries := recorder.Entries()
+	require.Len(t, entries, 3)
+	for _, entry := range entries {
+		require.True(t, strings.HasSuffix(entry.Caller.Function, "TestCallerSkip"), entry.Caller)
+		require.Equal(t, fmt.Sprint("line=", entry.Caller.Line), entry.Message)
+	}
+}
+
+func TestFatal(t *testing.T) {
+	log.SetFatalPolicy(log.FatalPolicy{Panic: true})
+	defer log.SetFatalPolicy(log.FatalPolicy{})
+	recorder := NewRecorder()
+	require.Panics(t, func() {
+		recorder.Fatalw("bye", "code", 2)
+	})
+	recorder.AssertLogged(t, log.FATAL, "bye code=2")
+}
+
+func TestConcurrentRecorder(t *testing.T) {
+	recorder := NewRecorder()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			child := recorder.With("worker", i)
+			for j := 0; j < 100; j++ {
+				child.Info("line")
+			}
+		}(i)
+	}
+	wg.Wait()
+	require.Len(t, recorder.Entries(), 800)
+}
@@ -0,0 +1,141 @@
+package log
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/stkali/utility/errors"
+)
+
+// anyLogger is the name of the rule that matches every logger without a more specific rule.
+const anyLogger = "*"
+
+var (
+	// levelRules holds the map[string]Level set by SetLevels, the map is never modified
+	// after it has been stored so that it can be read without locking on every log call.
+	levelRules atomic.Value
+	// levelRulesMtx serializes the writers of levelRules.
+	levelRulesMtx sync.Mutex
+)
+
+// joinName joins the name of a parent logger and the name of its child with a dot.
+func joinName(parent, name string) string {
+	switch {
+	case name == "":
+		return parent
+	case parent == "":
+		return name
+	default:
+		return parent + "." + name
+	}
+}
+
+// LookupLevel returns the level of the most specific rule matching the logger name.
+// A rule matches the logger with the same name and all its descendants, e.g. the rule
+// `net` matches `net`, `net.http` and `net.http.client`. Other Logger implementations use it
+// to apply the rules set by SetLevels like the standard logger.
+func LookupLevel(name string) (Level, bool) {
+	rules, _ := levelRules.Load().(map[string]Level)
+	if len(rules) == 0 {
+		return 0, false
+	}
+	for name != "" {
+		if lv, ok := rules[name]; ok {
+			return lv, true
+		}
+		index := strings.LastIndexByte(name, '.')
+		if index == -1 {
+			break
+		}
+		name = name[:index]
+	}
+	lv, ok := rules[anyLogger]
+	return lv, ok
+}
+
+// ParseLevels parses a comma-separated list of `name=level` rules such as
+// "storage=debug,net.http=warn,*=info". The name `*` matches every logger without a more
+// specific rule, a rule without name is the same as `*=level`. Level names are parsed like
+// ToLevel.
+func ParseLevels(spec string) (map[string]Level, error) {
+	rules := make(map[string]Level)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, level := anyLogger, item
+		if index := strings.IndexByte(item, '='); index != -1 {
+			name, level = strings.TrimSpace(item[:index]), strings.TrimSpace(item[index+1:])
+		}
+		if !validRuleName(name) {
+			return nil, errors.Newf("invalid logger name %q in level rule %q", name, item)
+		}
+		lv, ok := parseLevel(level)
+		if !ok {
+			return nil, errors.Newf("invalid level %q in level rule %q", level, item)
+		}
+		rules[name] = lv
+	}
+	return rules, nil
+}
+
+// validRuleName reports whether name can be the logger name of a level rule: it is not
+// empty, does not start or end with a dot and contains no separator of the rules.
+func validRuleName(name string) bool {
+	if name == "" || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") {
+		return false
+	}
+	return !strings.ContainsAny(name, ",= \t\r\n")
+}
+
+// SetLevels replaces the level rules of the named loggers with the rules parsed from spec,
+// see ParseLevels. The rules take precedence over the level set by SetLevel and apply on
+// every subsequent log call, including loggers created before. An empty spec removes all
+// rules.
+func SetLevels(spec string) error {
+	rules, err := ParseLevels(spec)
+	if err != nil {
+		return err
+	}
+	storeLevels(rules)
+	return nil
+}
+
+// storeLevels replaces the level rules, rules must not be modified afterwards.
+func storeLevels(rules map[string]Level) {
+	levelRulesMtx.Lock()
+	defer levelRulesMtx.Unlock()
+	levelRules.Store(rules)
+}
+
+// Levels returns a copy of the level rules set by SetLevels.
+func Levels() map[string]Level {
+	rules, _ := levelRules.Load().(map[string]Level)
+	cp := make(map[string]Level, len(rules))
+	for name, lv := range rules {
+		cp[name] = lv
+	}
+	return cp
+}
+
+// FormatLevels returns the rules in the format accepted by ParseLevels, sorted by name.
+func FormatLevels(rules map[string]Level) string {
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	for index, name := range names {
+		if index > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(name)
+		sb.WriteByte('=')
+		sb.WriteString(strings.ToLower(rules[name].Name()))
+	}
+	return sb.String()
+}
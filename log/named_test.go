@@ -0,0 +1,129 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLevels(t *testing.T) {
+	rules, err := ParseLevels(" storage=debug, net.http = Warning ,*=info,,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]Level{"storage": DEBUG, "net.http": WARN, "*": INFO}, rules)
+
+	rules, err = ParseLevels("error")
+	require.NoError(t, err)
+	require.Equal(t, map[string]Level{"*": ERROR}, rules)
+
+	rules, err = ParseLevels("")
+	require.NoError(t, err)
+	require.Empty(t, rules)
+
+	for _, spec := range []string{"storage=verbose", "=debug", ".net=info", "net.=info", "unknown"} {
+		_, err = ParseLevels(spec)
+		require.Error(t, err, spec)
+	}
+	require.Equal(t, "*=info,net.http=warn,storage=debug", FormatLevels(map[string]Level{"storage": DEBUG, "net.http": WARN, "*": INFO}))
+}
+
+func TestLookupLevel(t *testing.T) {
+	defer SetLevels("")
+	_, ok := LookupLevel("storage")
+	require.False(t, ok)
+
+	require.NoError(t, SetLevels("storage=debug,net.http=warn,net=error"))
+	cases := []struct {
+		name  string
+		level Level
+		ok    bool
+	}{
+		{"storage", DEBUG, true},
+		{"storage.db", DEBUG, true},
+		{"storages", 0, false},
+		{"net", ERROR, true},
+		{"net.http", WARN, true},
+		{"net.http.client", WARN, true},
+		{"net.rpc", ERROR, true},
+		{"", 0, false},
+	}
+	for _, c := range cases {
+		lv, ok := LookupLevel(c.name)
+		require.Equal(t, c.ok, ok, c.name)
+		require.Equal(t, c.level, lv, c.name)
+	}
+
+	require.NoError(t, SetLevels("storage=debug,*=info"))
+	lv, ok := LookupLevel("net.http")
+	require.True(t, ok)
+	require.Equal(t, INFO, lv)
+	lv, ok = LookupLevel("")
+	require.True(t, ok)
+	require.Equal(t, INFO, lv)
+
+	require.Error(t, SetLevels("storage=loud"))
+	require.Equal(t, map[string]Level{"storage": DEBUG, "*": INFO}, Levels())
+}
+
+func TestNamed(t *testing.T) {
+	recorder := new(bytes.Buffer)
+	SetOutput(recorder)
+	SetLevel(WARN)
+	SetFlags(0)
+	SetPrefix("")
+	defer SetLevels("")
+
+	storage := Named("storage")
+	db := storage.Named("db").With("table", "users")
+	http := Named("net").Named("").Named("http")
+
+	storage.Debug("ignored")
+	require.Equal(t, "", recorder.String())
+
+	require.NoError(t, SetLevels("storage=debug,net.http=error"))
+	db.Debugw("query", "ms", 12)
+	require.Equal(t, DEBUG.String()+"storage.db: query table=users ms=12\n", recorder.String())
+
+	recorder.Reset()
+	http.Warn("ignored")
+	Info("ignored")
+	require.Equal(t, "", recorder.String())
+
+	http.Error("failed")
+	require.Equal(t, ERROR.String()+"net.http: failed\n", recorder.String())
+
+	// rules are removed, the level of the logger applies again
+	recorder.Reset()
+	require.NoError(t, SetLevels(""))
+	db.Debug("ignored")
+	http.Warn("warned")
+	require.Equal(t, WARN.String()+"net.http: warned\n", recorder.String())
+}
+
+func TestNamedFollowsLevel(t *testing.T) {
+	defer SetLevel(WARN)
+	defer SetLevels("")
+	SetLevel(WARN)
+
+	// children created before SetLevel, e.g. package-level loggers
+	storage := Named("storage")
+	child := With("request", "r-1").Named("db")
+	helper := WithCallerSkip(1)
+	require.False(t, storage.(*defaultLogger).Enabled(DEBUG))
+
+	SetLevel(DEBUG)
+	for _, l := range []Logger{storage, child, helper} {
+		require.True(t, l.(*defaultLogger).Enabled(DEBUG))
+		require.False(t, l.(*defaultLogger).Enabled(TRACE))
+	}
+
+	// the rules still override the shared level
+	require.NoError(t, SetLevels("storage=error"))
+	require.False(t, storage.(*defaultLogger).Enabled(WARN))
+	require.True(t, child.(*defaultLogger).Enabled(DEBUG))
+
+	// SetLevel on a child is seen by its parent
+	require.NoError(t, SetLevels(""))
+	child.SetLevel(ERROR)
+	require.False(t, Enabled(WARN))
+}
@@ -0,0 +1,72 @@
+package log
+
+import "sync"
+
+const (
+	// maxPooledBuffer is the capacity above which an encoding buffer is not reused, so that
+	// a huge entry does not keep its memory alive.
+	maxPooledBuffer = 64 << 10
+	// maxPooledFields is the number of fields above which the fields buffer of an entry is
+	// not reused.
+	maxPooledFields = 64
+)
+
+// bufferPool holds the buffers the entries are encoded into.
+var bufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, 512)
+		return &buf
+	},
+}
+
+// getBuffer returns an empty buffer from the pool.
+func getBuffer() *[]byte {
+	buf := bufferPool.Get().(*[]byte)
+	*buf = (*buf)[:0]
+	return buf
+}
+
+// putBuffer returns the buffer to the pool, buf must not be used afterwards.
+func putBuffer(buf *[]byte) {
+	if cap(*buf) > maxPooledBuffer {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// pooledEntry is an entry reused across the log calls together with the buffer of the
+// fields of LogFields.
+type pooledEntry struct {
+	entry  Entry
+	fields []Field
+	// pc is the program counter of the caller given to LogwAt, used if atPC is set.
+	pc   uintptr
+	atPC bool
+}
+
+// entryPool holds the entries built by the loggers.
+var entryPool = sync.Pool{
+	New: func() any {
+		return &pooledEntry{}
+	},
+}
+
+// getEntry returns an empty entry from the pool.
+func getEntry() *pooledEntry {
+	return entryPool.Get().(*pooledEntry)
+}
+
+// putEntry clears the entry so that it does not retain the values logged and returns it to
+// the pool, p must not be used afterwards.
+func putEntry(p *pooledEntry) {
+	p.entry = Entry{}
+	p.pc, p.atPC = 0, false
+	if cap(p.fields) > maxPooledFields {
+		p.fields = nil
+	}
+	for index := range p.fields {
+		p.fields[index] = Field{}
+	}
+	p.fields = p.fields[:0]
+	entryPool.Put(p)
+}
@@ -0,0 +1,168 @@
+package log
+
+import (
+	"strconv"
+	"time"
+)
+
+// SampleKey selects how a sampler groups entries.
+type SampleKey int
+
+const (
+	// ByMessage groups entries by level and message.
+	ByMessage SampleKey = iota
+	// ByCaller groups entries by the file and line that emitted them.
+	ByCaller
+)
+
+// defaultTick is the sampling interval used when Sampling.Tick <= 0.
+const defaultTick = time.Second
+
+// Sampling configures the sampling and rate limiting of a logger.
+//
+// In every Tick, the First entries of each group are written, then every Thereafter-th entry
+// of the group is written. Independently, a token bucket limits the written entries to Rate
+// per second with bursts of Burst entries. FATAL entries are never suppressed.
+//
+// When entries have been suppressed, a WARN line "suppressed K messages" is written at the
+// end of the tick, before the first entry of the next tick if it comes first, and when the
+// output is flushed, e.g. before FATAL exits the program.
+type Sampling struct {
+	// Tick(default: 1s) is the interval at which sampling counters are reset.
+	Tick time.Duration
+	// First is the number of entries of each group written per tick.
+	// <= 0 means no sampling by group.
+	First int
+	// Thereafter is the rate of entries written once First is reached in a tick,
+	// e.g. 100 writes every 100th entry.
+	// <= 0 means no more entries of the group are written in the tick.
+	Thereafter int
+	// By selects how entries are grouped.
+	By SampleKey
+	// Rate is the number of entries per second allowed by the token bucket.
+	// <= 0 means no rate limiting.
+	Rate float64
+	// Burst is the capacity of the token bucket, it is at least 1.
+	Burst int
+}
+
+// sampleKey identifies a group of entries.
+type sampleKey struct {
+	level Level
+	text  string
+	line  int
+}
+
+// sampler implements Sampling, it is protected by the mutex of the core.
+type sampler struct {
+	option Sampling
+	// windowEnd is the end of the current tick.
+	windowEnd time.Time
+	counts    map[sampleKey]int
+	// suppressed counts the entries suppressed since the last summary, lastSuppressed is the
+	// latest of them, its name and caller are reported by the summaries written without a
+	// next entry.
+	suppressed     int
+	lastSuppressed Entry
+	// tokens and last are the state of the token bucket.
+	tokens float64
+	last   time.Time
+	// timer reports the suppressed entries at the end of the tick if no entry follows them.
+	timer *time.Timer
+}
+
+func newSampler(option Sampling) *sampler {
+	if option.Tick <= 0 {
+		option.Tick = defaultTick
+	}
+	if option.Burst < 1 {
+		option.Burst = 1
+	}
+	return &sampler{
+		option: option,
+		counts: make(map[sampleKey]int),
+		tokens: float64(option.Burst),
+	}
+}
+
+// sample reports whether the entry must be written. When a new tick starts and entries
+// have been suppressed in the previous one, it also returns the number of suppressed entries.
+func (s *sampler) sample(entry *Entry) (allowed bool, suppressed int) {
+	now := entry.Time
+	if !now.Before(s.windowEnd) {
+		suppressed = s.flush()
+		if len(s.counts) > 0 {
+			s.counts = make(map[sampleKey]int)
+		}
+		s.windowEnd = now.Add(s.option.Tick)
+	}
+	if entry.Level == FATAL {
+		return true, suppressed
+	}
+	if s.allowGroup(entry) && s.allowRate(now) {
+		return true, suppressed
+	}
+	s.suppressed++
+	s.lastSuppressed = Entry{Time: now, Name: entry.Name, Caller: entry.Caller}
+	return false, suppressed
+}
+
+// flush returns the number of entries suppressed since the last summary and resets it.
+func (s *sampler) flush() int {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	suppressed := s.suppressed
+	s.suppressed = 0
+	return suppressed
+}
+
+// allowGroup applies the First/Thereafter sampling to the group of the entry.
+func (s *sampler) allowGroup(entry *Entry) bool {
+	if s.option.First <= 0 {
+		return true
+	}
+	key := sampleKey{level: entry.Level}
+	if s.option.By == ByCaller {
+		key.text, key.line = entry.Caller.File, entry.Caller.Line
+	} else {
+		key.text = entry.Message
+	}
+	n := s.counts[key] + 1
+	s.counts[key] = n
+	if n <= s.option.First {
+		return true
+	}
+	return s.option.Thereafter > 0 && (n-s.option.First)%s.option.Thereafter == 0
+}
+
+// allowRate takes a token from the bucket.
+func (s *sampler) allowRate(now time.Time) bool {
+	if s.option.Rate <= 0 {
+		return true
+	}
+	if !s.last.IsZero() {
+		s.tokens += now.Sub(s.last).Seconds() * s.option.Rate
+		if burst := float64(s.option.Burst); s.tokens > burst {
+			s.tokens = burst
+		}
+	}
+	s.last = now
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+// summary returns the entry reporting the suppressed entries before entry.
+func summary(entry *Entry, suppressed int) Entry {
+	return Entry{
+		Time:    entry.Time,
+		Level:   WARN,
+		Name:    entry.Name,
+		Caller:  entry.Caller,
+		Message: "suppressed " + strconv.Itoa(suppressed) + " messages",
+	}
+}
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSamplerGroup(t *testing.T) {
+	start := time.Now()
+	s := newSampler(Sampling{Tick: time.Second, First: 2, Thereafter: 3})
+	require.Equal(t, 1, s.option.Burst)
+
+	entry := &Entry{Time: start, Level: INFO, Message: "hot"}
+	var written []int
+	for i := 1; i <= 10; i++ {
+		allowed, suppressed := s.sample(entry)
+		require.Equal(t, 0, suppressed)
+		if allowed {
+			written = append(written, i)
+		}
+	}
+	// first 2, then every 3rd
+	require.Equal(t, []int{1, 2, 5, 8}, written)
+
+	// other groups are counted separately
+	allowed, _ := s.sample(&Entry{Time: start, Level: WARN, Message: "hot"})
+	require.True(t, allowed)
+	allowed, _ = s.sample(&Entry{Time: start, Level: INFO, Message: "cold"})
+	require.True(t, allowed)
+
+	// FATAL is never suppressed
+	for i := 0; i < 5; i++ {
+		allowed, _ = s.sample(&Entry{Time: start, Level: FATAL, Message: "fatal"})
+		require.True(t, allowed)
+	}
+
+	// a new tick resets the counters and reports the suppressed entries
+	entry.Time = start.Add(time.Second)
+	allowed, suppressed := s.sample(entry)
+	require.True(t, allowed)
+	require.Equal(t, 6, suppressed)
+	_, suppressed = s.sample(entry)
+	require.Equal(t, 0, suppressed)
+}
+
+func TestSamplerCaller(t *testing.T) {
+	now := time.Now()
+	s := newSampler(Sampling{First: 1, By: ByCaller})
+	require.Equal(t, defaultTick, s.option.Tick)
+	first := Caller{File: "a.go", Line: 1}
+	allowed, _ := s.sample(&Entry{Time: now, Caller: first, Message: "a"})
+	require.True(t, allowed)
+	// same call site, different message
+	allowed, _ = s.sample(&Entry{Time: now, Caller: first, Message: "b"})
+	require.False(t, allowed)
+	allowed, _ = s.sample(&Entry{Time: now, Caller: Caller{File: "a.go", Line: 2}, Message: "a"})
+	require.True(t, allowed)
+}
+
+func TestSamplerRate(t *testing.T) {
+	start := time.Now()
+	s := newSampler(Sampling{Tick: time.Hour, Rate: 10, Burst: 3})
+	count := func(at time.Time, n int) int {
+		written := 0
+		for i := 0; i < n; i++ {
+			if allowed, _ := s.sample(&Entry{Time: at, Message: "x"}); allowed {
+				written++
+			}
+		}
+		return written
+	}
+	require.Equal(t, 3, count(start, 10))
+	// 10 per second refills 1 token in 100ms
+	require.Equal(t, 1, count(start.Add(100*time.Millisecond), 10))
+	// the bucket does not exceed the burst
+	require.Equal(t, 3, count(start.Add(time.Minute), 10))
+	require.Equal(t, 23, s.suppressed)
+}
+
+func TestSetSampling(t *testing.T) {
+	recorder := new(bytes.Buffer)
+	SetOutput(recorder)
+	SetLevel(INFO)
+	SetFlags(0)
+	SetPrefix("")
+	defer SetSampling(nil)
+
+	SetSampling(&Sampling{Tick: 50 * time.Millisecond, First: 2})
+	for i := 0; i < 10; i++ {
+		Warn("hot loop")
+	}
+	require.Equal(t, strings.Repeat(WARN.String()+"hot loop\n", 2), recorder.String())
+
+	// the suppressed entries are reported at the end of the tick, without waiting for an entry
+	core := DefaultLogger().(*defaultLogger).core
+	output := func() string {
+		core.mtx.Lock()
+		defer core.mtx.Unlock()
+		return recorder.String()
+	}
+	require.Eventually(t, func() bool {
+		return strings.HasSuffix(output(), WARN.String()+"suppressed 8 messages\n")
+	}, time.Second, 10*time.Millisecond)
+	core.mtx.Lock()
+	recorder.Reset()
+	core.mtx.Unlock()
+	Info("next")
+	require.Equal(t, INFO.String()+"next\n", recorder.String())
+
+	// before the end of the tick, the next entry or a flush reports them
+	SetSampling(&Sampling{Tick: time.Hour, First: 1})
+	recorder.Reset()
+	for i := 0; i < 3; i++ {
+		Warn("burst")
+	}
+	Warn("other")
+	require.Equal(t, WARN.String()+"burst\n"+WARN.String()+"other\n", output())
+	core.flush()
+	require.Equal(t, WARN.String()+"burst\n"+WARN.String()+"other\n"+WARN.String()+"suppressed 2 messages\n", output())
+	core.flush()
+	require.Equal(t, 3, strings.Count(output(), "\n"))
+
+	// sampling by caller resolves the caller even without file flags
+	SetSampling(&Sampling{First: 1, By: ByCaller})
+	recorder.Reset()
+	for i := 0; i < 3; i++ {
+		Infof("call %d", i)
+	}
+	require.Equal(t, INFO.String()+"call 0\n", recorder.String())
+
+	// disabled sampling
+	SetSampling(nil)
+	recorder.Reset()
+	for i := 0; i < 3; i++ {
+		Info("x")
+	}
+	require.Equal(t, 3, strings.Count(recorder.String(), "\n"))
+}
@@ -0,0 +1,459 @@
+// Copyright 2021-2024 The utility Authors. All rights reserved.
+// Use of this source code is governed by the MIT license that can be found in the
+// LICENSE file
+
+//go:build go1.21
+
+// Package slogbridge connects the log package with log/slog of the standard library.
+// Handler exposes a log.Logger as a slog.Handler and Logger exposes any slog.Handler
+// as a log.Logger.
+//
+// The package requires go >= 1.21, so that the log package keeps supporting go 1.18.
+package slogbridge
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/stkali/utility/log"
+)
+
+// ToLogLevel maps a slog.Level onto the levels of the log package:
+//
+//	     level < LevelDebug   -> TRACE
+//	LevelDebug <= level < LevelInfo  -> DEBUG
+//	 LevelInfo <= level < LevelWarn  -> INFO
+//	 LevelWarn <= level < LevelError -> WARN
+//	LevelError <= level              -> ERROR
+//
+// FATAL is never returned: slog has no level that exits the program, so the levels above
+// LevelError, such as a custom CRITICAL level, are logged with ERROR.
+func ToLogLevel(level slog.Level) log.Level {
+	switch {
+	case level < slog.LevelDebug:
+		return log.TRACE
+	case level < slog.LevelInfo:
+		return log.DEBUG
+	case level < slog.LevelWarn:
+		return log.INFO
+	case level < slog.LevelError:
+		return log.WARN
+	default:
+		return log.ERROR
+	}
+}
+
+// ToSlogLevel maps a level of the log package onto a slog.Level, it is the inverse of
+// ToLogLevel for TRACE..ERROR, FATAL maps to LevelError+4. Levels registered by log.RegisterLevel are mapped by their
+// severity, e.g. a severity of 250 (between INFO and WARN) maps to LevelInfo+2.
+func ToSlogLevel(level log.Level) slog.Level {
+	return slog.LevelInfo + slog.Level(4*(level.Severity()-log.INFO.Severity())/100)
+}
+
+// Handler is a slog.Handler that writes records to a log.Logger.
+// Attributes are converted to the structured fields of the logger, attributes in groups
+// are rendered with the keys joined by dots, e.g. `request.id`.
+type Handler struct {
+	logger log.Logger
+	// group is the prefix of the keys of the attributes, it ends with a dot.
+	group string
+}
+
+var _ slog.Handler = (*Handler)(nil)
+
+// callerLogger is implemented by the loggers that log an entry with the caller at a given
+// program counter.
+type callerLogger interface {
+	LogwAt(pc uintptr, level log.Level, msg string, keysAndValues ...any)
+}
+
+// NewHandler returns a slog.Handler that writes to the logger.
+func NewHandler(logger log.Logger) *Handler {
+	return &Handler{logger: logger}
+}
+
+// levelLogger is implemented by the loggers that report whether they log entries of a
+// level, like the loggers of the log package.
+type levelLogger interface {
+	Enabled(level log.Level) bool
+}
+
+// Enabled implements the slog.Handler interface.
+// It asks the underlying logger if it supports it, including the rules set by
+// log.SetLevels, otherwise it returns true and the level is filtered by the logger.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	if logger, ok := h.logger.(levelLogger); ok {
+		return logger.Enabled(ToLogLevel(level))
+	}
+	return true
+}
+
+// Handle implements the slog.Handler interface.
+// The fields of ctx (see log.ContextFields) are logged before the attributes of the record.
+// The caller of the record is its PC if the logger supports it, like the loggers of the
+// log package, otherwise the caller resolved by the logger.
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	fields := log.ContextFields(ctx)
+	kvs := make([]any, 0, len(fields)+2*record.NumAttrs())
+	for _, field := range fields {
+		kvs = append(kvs, field)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		kvs = appendAttr(kvs, h.group, attr)
+		return true
+	})
+	if logger, ok := h.logger.(callerLogger); ok {
+		logger.LogwAt(record.PC, ToLogLevel(record.Level), record.Message, kvs...)
+		return nil
+	}
+	switch ToLogLevel(record.Level) {
+	case log.TRACE:
+		h.logger.Tracew(record.Message, kvs...)
+	case log.DEBUG:
+		h.logger.Debugw(record.Message, kvs...)
+	case log.INFO:
+		h.logger.Infow(record.Message, kvs...)
+	case log.WARN:
+		h.logger.Warnw(record.Message, kvs...)
+	default:
+		h.logger.Errorw(record.Message, kvs...)
+	}
+	return nil
+}
+
+// WithAttrs implements the slog.Handler interface.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	kvs := make([]any, 0, 2*len(attrs))
+	for _, attr := range attrs {
+		kvs = appendAttr(kvs, h.group, attr)
+	}
+	return &Handler{logger: h.logger.With(kvs...), group: h.group}
+}
+
+// WithGroup implements the slog.Handler interface.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &Handler{logger: h.logger, group: h.group + name + "."}
+}
+
+// appendAttr appends the resolved attribute to kvs as key and value, groups are flattened.
+func appendAttr(kvs []any, group string, attr slog.Attr) []any {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return kvs
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			group += attr.Key + "."
+		}
+		for _, member := range attr.Value.Group() {
+			kvs = appendAttr(kvs, group, member)
+		}
+		return kvs
+	}
+	return append(kvs, group+attr.Key, attr.Value.Any())
+}
+
+// Logger is a log.Logger that writes to a slog.Handler.
+// The output, prefix, flags and encoder are owned by the handler, so SetOutput, SetPrefix,
+// SetFlags and SetEncoder have no effect. The name given by Named is added to every record
+// as the `logger` attribute.
+type Logger struct {
+	handler slog.Handler
+	// level is the log.Level threshold of the logger, it is accessed atomically and shared
+	// with the children created by With, Named and WithCallerSkip.
+	level *int32
+	name  string
+	// callerSkip is the number of additional frames skipped to find the caller, see
+	// WithCallerSkip.
+	callerSkip int
+}
+
+var _ log.Logger = (*Logger)(nil)
+
+// logPackage is the prefix of the functions of the log package, they are skipped when
+// resolving the caller so that the package-level functions report their own caller.
+const logPackage = "github.com/stkali/utility/log."
+
+// NewLogger returns a log.Logger that writes to the handler, the initial level is TRACE
+// so that filtering is left to the handler.
+func NewLogger(handler slog.Handler) *Logger {
+	level := int32(log.TRACE)
+	return &Logger{handler: handler, level: &level}
+}
+
+// Handler returns the underlying slog.Handler.
+func (l *Logger) Handler() slog.Handler {
+	return l.handler
+}
+
+// log sends a record to the handler if the level is enabled by the logger and the handler.
+func (l *Logger) log(lv log.Level, msg string, kvs []any) {
+	if !l.levelEnabled(lv) {
+		return
+	}
+	ctx := context.Background()
+	level := ToSlogLevel(lv)
+	if !l.handler.Enabled(ctx, level) {
+		return
+	}
+	record := slog.NewRecord(time.Now(), level, msg, l.callerPC())
+	if l.name != "" {
+		record.AddAttrs(slog.String("logger", l.name))
+	}
+	record.Add(expandFields(kvs)...)
+	_ = l.handler.Handle(ctx, record)
+	if lv == log.FATAL {
+		log.FatalExit(msg)
+	}
+}
+
+// callerPC returns the program counter of the caller of the Logger method, skipping the
+// package-level functions of the log package and the frames added by WithCallerSkip.
+func (l *Logger) callerPC() uintptr {
+	var pcs [16]uintptr
+	// skip runtime.Callers, callerPC, log, logf/logw/logFields and the Logger method
+	n := runtime.Callers(5, pcs[:])
+	for index := 0; index < n; index++ {
+		if fn := runtime.FuncForPC(pcs[index] - 1); fn != nil && strings.HasPrefix(fn.Name(), logPackage) {
+			continue
+		}
+		if index += l.callerSkip; index < n {
+			return pcs[index]
+		}
+		break
+	}
+	return 0
+}
+
+func (l *Logger) logf(lv log.Level, format *string, args ...any) {
+	if !l.levelEnabled(lv) {
+		return
+	}
+	var msg string
+	if format != nil {
+		msg = fmt.Sprintf(*format, args...)
+	} else {
+		msg = fmt.Sprint(args...)
+	}
+	l.log(lv, msg, nil)
+}
+
+func (l *Logger) logw(lv log.Level, msg string, keysAndValues ...any) {
+	l.log(lv, msg, keysAndValues)
+}
+
+func (l *Logger) logFields(lv log.Level, msg string, fields []log.Field) {
+	if !l.levelEnabled(lv) {
+		return
+	}
+	kvs := make([]any, 0, 2*len(fields))
+	for index := range fields {
+		kvs = append(kvs, fields[index].Key, fields[index].Any())
+	}
+	l.log(lv, msg, kvs)
+}
+
+// levelEnabled reports whether the level is enabled by the rules set by log.SetLevels for
+// the name of the logger, or by the level of the logger if no rule matches.
+func (l *Logger) levelEnabled(lv log.Level) bool {
+	threshold, ok := log.LookupLevel(l.name)
+	if !ok {
+		threshold = l.GetLevel()
+	}
+	return lv.Severity() >= threshold.Severity()
+}
+
+// Enabled reports whether the logger and the handler handle entries of the level, the
+// rules set by log.SetLevels override the level of the logger.
+func (l *Logger) Enabled(lv log.Level) bool {
+	if !l.levelEnabled(lv) {
+		return false
+	}
+	return l.handler.Enabled(context.Background(), ToSlogLevel(lv))
+}
+
+// With implements the log.Logger interface, the key/value pairs are bound to the handler
+// with WithAttrs.
+func (l *Logger) With(keysAndValues ...any) log.Logger {
+	record := slog.Record{}
+	record.Add(expandFields(keysAndValues)...)
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	return &Logger{handler: l.handler.WithAttrs(attrs), level: l.level, name: l.name, callerSkip: l.callerSkip}
+}
+
+// WithContext implements the log.Logger interface, the fields of ctx are bound to the
+// handler like With.
+func (l *Logger) WithContext(ctx context.Context) log.Logger {
+	fields := log.ContextFields(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+	kvs := make([]any, len(fields))
+	for index := range fields {
+		kvs[index] = fields[index]
+	}
+	return l.With(kvs...)
+}
+
+// Named implements the log.Logger interface, the names are joined by dots.
+func (l *Logger) Named(name string) log.Logger {
+	if name == "" {
+		return l
+	}
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	return &Logger{handler: l.handler, level: l.level, name: name, callerSkip: l.callerSkip}
+}
+
+// WithCallerSkip returns a child logger that skips n additional frames to find the caller
+// of its records, see log.WithCallerSkip.
+func (l *Logger) WithCallerSkip(n int) log.Logger {
+	return &Logger{handler: l.handler, level: l.level, name: l.name, callerSkip: l.callerSkip + n}
+}
+
+// expandFields replaces every log.Field in keysAndValues by its key and value, so that
+// they are understood by slog.Record.Add.
+func expandFields(keysAndValues []any) []any {
+	count := 0
+	for _, kv := range keysAndValues {
+		if _, ok := kv.(log.Field); ok {
+			count++
+		}
+	}
+	if count == 0 {
+		return keysAndValues
+	}
+	expanded := make([]any, 0, len(keysAndValues)+count)
+	for _, kv := range keysAndValues {
+		if field, ok := kv.(log.Field); ok {
+			expanded = append(expanded, field.Key, field.Any())
+		} else {
+			expanded = append(expanded, kv)
+		}
+	}
+	return expanded
+}
+
+// SetLevel sets the level of the logger and of the loggers sharing it, it is safe to call concurrently with logging.
+func (l *Logger) SetLevel(lv log.Level) {
+	atomic.StoreInt32(l.level, int32(lv))
+}
+
+// GetLevel returns the level set by SetLevel.
+func (l *Logger) GetLevel() log.Level {
+	return log.Level(atomic.LoadInt32(l.level))
+}
+
+func (l *Logger) SetOutput(io.Writer) {}
+
+func (l *Logger) SetPrefix(string) {}
+
+func (l *Logger) SetFlags(int) {}
+
+func (l *Logger) SetEncoder(log.Encoder) {}
+
+func (l *Logger) Fatal(args ...any) {
+	l.logf(log.FATAL, nil, args...)
+}
+
+func (l *Logger) Error(args ...any) {
+	l.logf(log.ERROR, nil, args...)
+}
+
+func (l *Logger) Warn(args ...any) {
+	l.logf(log.WARN, nil, args...)
+}
+
+func (l *Logger) Info(args ...any) {
+	l.logf(log.INFO, nil, args...)
+}
+
+func (l *Logger) Debug(args ...any) {
+	l.logf(log.DEBUG, nil, args...)
+}
+
+func (l *Logger) Trace(args ...any) {
+	l.logf(log.TRACE, nil, args...)
+}
+
+func (l *Logger) Fatalf(format string, args ...any) {
+	l.logf(log.FATAL, &format, args...)
+}
+
+func (l *Logger) Errorf(format string, args ...any) {
+	l.logf(log.ERROR, &format, args...)
+}
+
+func (l *Logger) Warnf(format string, args ...any) {
+	l.logf(log.WARN, &format, args...)
+}
+
+func (l *Logger) Infof(format string, args ...any) {
+	l.logf(log.INFO, &format, args...)
+}
+
+func (l *Logger) Debugf(format string, args ...any) {
+	l.logf(log.DEBUG, &format, args...)
+}
+
+func (l *Logger) Tracef(format string, args ...any) {
+	l.logf(log.TRACE, &format, args...)
+}
+
+func (l *Logger) Fatalw(msg string, keysAndValues ...any) {
+	l.logw(log.FATAL, msg, keysAndValues...)
+}
+
+func (l *Logger) Errorw(msg string, keysAndValues ...any) {
+	l.logw(log.ERROR, msg, keysAndValues...)
+}
+
+func (l *Logger) Warnw(msg string, keysAndValues ...any) {
+	l.logw(log.WARN, msg, keysAndValues...)
+}
+
+func (l *Logger) Infow(msg string, keysAndValues ...any) {
+	l.logw(log.INFO, msg, keysAndValues...)
+}
+
+func (l *Logger) Debugw(msg string, keysAndValues ...any) {
+	l.logw(log.DEBUG, msg, keysAndValues...)
+}
+
+func (l *Logger) Tracew(msg string, keysAndValues ...any) {
+	l.logw(log.TRACE, msg, keysAndValues...)
+}
+
+func (l *Logger) Log(level log.Level, args ...any) {
+	l.logf(level, nil, args...)
+}
+
+func (l *Logger) Logf(level log.Level, format string, args ...any) {
+	l.logf(level, &format, args...)
+}
+
+func (l *Logger) Logw(level log.Level, msg string, keysAndValues ...any) {
+	l.logw(level, msg, keysAndValues...)
+}
+
+// LogFields logs the message with the fields at the level, see log.LogFields.
+func (l *Logger) LogFields(level log.Level, msg string, fields ...log.Field) {
+	l.logFields(level, msg, fields)
+}
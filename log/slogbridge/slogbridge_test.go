@@ -0,0 +1,283 @@
+//go:build go1.21
+
+package slogbridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/stkali/utility/log"
+	"github.com/stkali/utility/log/internal/callertest"
+)
+
+func TestMain(m *testing.M) {
+	preExit := log.Exit
+	log.Exit = func(code int) {}
+	code := m.Run()
+	preExit(code)
+}
+
+func TestLevelMapping(t *testing.T) {
+	cases := []struct {
+		slog slog.Level
+		log  log.Level
+	}{
+		{slog.LevelDebug - 4, log.TRACE},
+		{slog.LevelDebug - 1, log.TRACE},
+		{slog.LevelDebug, log.DEBUG},
+		{slog.LevelInfo, log.INFO},
+		{slog.LevelInfo + 1, log.INFO},
+		{slog.LevelWarn, log.WARN},
+		{slog.LevelError, log.ERROR},
+		{slog.LevelError + 4, log.ERROR},
+		{slog.LevelError + 10, log.ERROR},
+	}
+	for _, c := range cases {
+		t.Run(c.slog.String(), func(t *testing.T) {
+			require.Equal(t, c.log, ToLogLevel(c.slog))
+		})
+	}
+	for lv := log.TRACE; lv <= log.ERROR; lv++ {
+		require.Equal(t, lv, ToLogLevel(ToSlogLevel(lv)))
+	}
+	require.Equal(t, slog.LevelDebug, ToSlogLevel(log.DEBUG))
+	require.Equal(t, slog.LevelError, ToSlogLevel(log.ERROR))
+	require.Equal(t, slog.LevelError+4, ToSlogLevel(log.FATAL))
+
+	notice, err := log.RegisterLevel("notice", 250, "")
+	if err != nil {
+		// registered by a previous run with -count
+		notice = log.ToLevel("notice")
+	}
+	require.Equal(t, slog.LevelInfo+2, ToSlogLevel(notice))
+	require.Equal(t, log.INFO, ToLogLevel(ToSlogLevel(notice)))
+}
+
+func TestHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := log.DefaultLogger()
+	logger.SetOutput(buf)
+	logger.SetFlags(0)
+	logger.SetPrefix("")
+	logger.SetLevel(log.DEBUG)
+	defer logger.SetOutput(os.Stdout)
+
+	sl := slog.New(NewHandler(logger))
+	require.True(t, sl.Enabled(context.Background(), slog.LevelDebug))
+	require.False(t, sl.Enabled(context.Background(), slog.LevelDebug-4))
+
+	sl.Info("hello", "user", "alice", slog.Int("id", 1))
+	require.Equal(t, "[INFO ] hello user=alice id=1\n", buf.String())
+
+	buf.Reset()
+	sl.Log(context.Background(), slog.LevelDebug-4, "dropped")
+	require.Equal(t, "", buf.String())
+
+	buf.Reset()
+	sl.With("request", "r-1").WithGroup("db").With("table", "users").
+		Warn("slow", slog.Group("query", "ms", 12), slog.Group("", "inline", true), slog.Attr{})
+	require.Equal(t, "[WARN ] slow request=r-1 db.table=users db.query.ms=12 db.inline=true\n", buf.String())
+
+	buf.Reset()
+	ctx := log.NewContext(context.Background(), "request", "r-2")
+	sl.InfoContext(ctx, "with context", "k", 1)
+	require.Equal(t, "[INFO ] with context request=r-2 k=1\n", buf.String())
+
+	buf.Reset()
+	sl.WithGroup("").With().Error("failed")
+	require.Equal(t, "[ERROR] failed\n", buf.String())
+
+	buf.Reset()
+	// a custom level above LevelError is logged with ERROR and does not exit
+	exited := false
+	preExit := log.Exit
+	log.Exit = func(code int) { exited = true }
+	defer func() { log.Exit = preExit }()
+	sl.Log(context.Background(), slog.LevelError+4, "critical")
+	require.Equal(t, "[ERROR] critical\n", buf.String())
+	require.False(t, exited)
+}
+
+func TestLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	handler := slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug - 4, AddSource: true})
+	logger := NewLogger(handler)
+	require.Equal(t, handler, logger.Handler())
+
+	decode := func() map[string]any {
+		t.Helper()
+		var got map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+		buf.Reset()
+		return got
+	}
+
+	logger.Infow("hello", "user", "alice", log.Field{Key: "id", Value: 1})
+	got := decode()
+	require.Equal(t, "INFO", got["level"])
+	require.Equal(t, "hello", got["msg"])
+	require.Equal(t, "alice", got["user"])
+	require.Equal(t, float64(1), got["id"])
+	source := got["source"].(map[string]any)
+	require.True(t, strings.HasSuffix(source["file"].(string), "slogbridge_test.go"))
+
+	child := logger.With("request", "r-1", log.Field{Key: "step", Value: 2})
+	child.Errorf("failed after %d retries", 3)
+	got = decode()
+	require.Equal(t, "ERROR", got["level"])
+	require.Equal(t, "failed after 3 retries", got["msg"])
+	require.Equal(t, "r-1", got["request"])
+	require.Equal(t, float64(2), got["step"])
+
+	named := logger.Named("storage").Named("").Named("db").With("table", "users")
+	named.Warn("slow")
+	got = decode()
+	require.Equal(t, "storage.db", got["logger"])
+	require.Equal(t, "users", got["table"])
+
+	logger.WithContext(context.Background()).Info("no context")
+	require.NotContains(t, decode(), "request")
+	logger.WithContext(log.NewContext(context.Background(), "request", "r-3")).Info("context")
+	require.Equal(t, "r-3", decode()["request"])
+
+	logger.LogFields(log.WARN, "typed", log.Int("n", 3), log.Str("user", "bob"), log.Dur("cost", time.Second))
+	got = decode()
+	require.Equal(t, "WARN", got["level"])
+	require.Equal(t, float64(3), got["n"])
+	require.Equal(t, "bob", got["user"])
+	require.Equal(t, float64(time.Second), got["cost"])
+	source = got["source"].(map[string]any)
+	require.True(t, strings.HasSuffix(source["file"].(string), "slogbridge_test.go"))
+
+	methods := []struct {
+		level string
+		log   func(...any)
+		logf  func(string, ...any)
+		logw  func(string, ...any)
+	}{
+		{"DEBUG-4", logger.Trace, logger.Tracef, logger.Tracew},
+		{"DEBUG", logger.Debug, logger.Debugf, logger.Debugw},
+		{"INFO", logger.Info, logger.Infof, logger.Infow},
+		{"WARN", logger.Warn, logger.Warnf, logger.Warnw},
+		{"ERROR", logger.Error, logger.Errorf, logger.Errorw},
+		{"ERROR+4", logger.Fatal, logger.Fatalf, logger.Fatalw},
+	}
+	for _, m := range methods {
+		m.log("a", "b")
+		require.Equal(t, "ab", decode()["msg"])
+		m.logf("%s-%d", "a", 1)
+		require.Equal(t, "a-1", decode()["msg"])
+		m.logw("msg", "k", "v")
+		got = decode()
+		require.Equal(t, m.level, got["level"])
+		require.Equal(t, "v", got["k"])
+	}
+
+	// level of the logger
+	logger.SetLevel(log.WARN)
+	logger.Info("ignored")
+	logger.Infof("ignored")
+	logger.LogFields(log.INFO, "ignored", log.Int("n", 1))
+	require.Equal(t, 0, buf.Len())
+	require.False(t, logger.Enabled(log.INFO))
+	require.True(t, logger.Enabled(log.WARN))
+	// the children created before share the level
+	named.Info("ignored")
+	require.Equal(t, 0, buf.Len())
+	require.False(t, named.(*Logger).Enabled(log.INFO))
+
+	// the rules of the named loggers override the level of the logger
+	require.NoError(t, log.SetLevels("storage=debug,*=error"))
+	storage := logger.Named("storage")
+	require.True(t, storage.(*Logger).Enabled(log.DEBUG))
+	storage.Debug("query")
+	require.Equal(t, "query", decode()["msg"])
+	logger.Warn("ignored")
+	require.Equal(t, 0, buf.Len())
+	require.False(t, logger.Enabled(log.WARN))
+	require.NoError(t, log.SetLevels(""))
+
+	// level of the handler
+	quiet := NewLogger(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelError}))
+	quiet.Warn("ignored")
+	require.Equal(t, 0, buf.Len())
+	require.False(t, quiet.Enabled(log.WARN))
+	require.True(t, quiet.Enabled(log.ERROR))
+
+	// settings owned by the handler
+	logger.SetOutput(os.Stdout)
+	logger.SetPrefix("prefix")
+	logger.SetFlags(log.LstdFlags)
+	logger.SetEncoder(log.NewJSONEncoder())
+	logger.Warn("still json")
+	require.Equal(t, "still json", decode()["msg"])
+}
+
+// callerPattern matches a line logged with Lshortfile and the `line` attribute, possibly in a
+// group.
+var callerPattern = regexp.MustCompile(`^(\w+\.go):(\d+): .*line=(\d+)\n$`)
+
+func TestHandlerCaller(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := log.DefaultLogger()
+	logger.SetOutput(buf)
+	logger.SetFlags(log.Lshortfile)
+	logger.SetPrefix("")
+	logger.SetLevel(log.DEBUG)
+	defer logger.SetOutput(os.Stdout)
+	requireCaller := func() {
+		t.Helper()
+		groups := callerPattern.FindStringSubmatch(buf.String())
+		require.NotNil(t, groups, buf.String())
+		require.Equal(t, "slogbridge_test.go", groups[1])
+		require.Equal(t, groups[3], groups[2])
+		buf.Reset()
+	}
+
+	sl := slog.New(NewHandler(logger.Named("slog")))
+	sl.Info("hello", "line", callertest.Line())
+	requireCaller()
+	sl.With("k", "v").WithGroup("g").Warn("with", slog.Int("line", callertest.Line()))
+	requireCaller()
+
+	// a record without PC has no caller
+	require.NoError(t, NewHandler(logger).Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "no pc", 0)))
+	require.Equal(t, "???:0: [INFO ] no pc\n", buf.String())
+}
+
+func TestLoggerCaller(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewLogger(slog.NewJSONHandler(buf, &slog.HandlerOptions{AddSource: true}))
+	requireCaller := func() {
+		t.Helper()
+		var got map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+		buf.Reset()
+		source := got["source"].(map[string]any)
+		require.True(t, strings.HasSuffix(source["function"].(string), "TestLoggerCaller"), source)
+		require.Equal(t, fmt.Sprint("line=", source["line"]), got["msg"])
+	}
+
+	// the package-level functions report their caller
+	defer log.SetLogger(log.DefaultLogger())
+	log.SetLogger(logger)
+	log.Warn("line=", callertest.Line())
+	requireCaller()
+
+	// the helpers report the line calling them
+	log.SetLogger(logger.WithCallerSkip(1))
+	callertest.Helper(log.Warn, "line=", callertest.Line())
+	requireCaller()
+	log.WithContext(log.NewContext(context.Background(), "k", "v")).Named("child").(*Logger).WithCallerSkip(-1).Warn("line=", callertest.Line())
+	requireCaller()
+}
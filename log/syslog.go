@@ -0,0 +1,367 @@
+package log
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/stkali/utility/errors"
+)
+
+// Facility is the syslog facility of the entries, it tells the syslog daemon which kind of
+// program logged them.
+type Facility int
+
+const (
+	FacilityKern Facility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLpr
+	FacilityNews
+	FacilityUucp
+	FacilityCron
+	FacilityAuthpriv
+	FacilityFtp
+)
+
+const (
+	FacilityLocal0 Facility = iota + 16
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// SyslogFormat is the format of the lines rendered by a SyslogEncoder.
+type SyslogFormat int
+
+const (
+	// RFC5424 is the format of the syslog protocol, the fields are rendered as structured data.
+	RFC5424 SyslogFormat = iota
+	// RFC3164 is the legacy BSD format, the fields are appended to the message.
+	RFC3164
+)
+
+const (
+	// syslogSDID is the ID of the structured data holding the fields in the RFC5424 format,
+	// 32473 is the private enterprise number reserved for documentation.
+	syslogSDID = "fields@32473"
+	// rfc5424Time is the time layout of the RFC5424 format, limited to microseconds.
+	rfc5424Time = "2006-01-02T15:04:05.000000Z07:00"
+	// syslogNil is the value of the empty header fields of the RFC5424 format.
+	syslogNil = "-"
+)
+
+// syslog severities
+const (
+	severityCrit    = 2
+	severityErr     = 3
+	severityWarning = 4
+	severityNotice  = 5
+	severityInfo    = 6
+	severityDebug   = 7
+)
+
+// syslogSeverity returns the syslog severity of the level: TRACE and DEBUG are debug, INFO
+// is info, WARN is warning, ERROR is err and FATAL is crit. The custom levels between INFO
+// and WARN are notice, the others use the severity of the built-in level below them.
+func syslogSeverity(lv Level) int {
+	severity := lv.Severity()
+	switch {
+	case severity >= FATAL.Severity():
+		return severityCrit
+	case severity >= ERROR.Severity():
+		return severityErr
+	case severity >= WARN.Severity():
+		return severityWarning
+	case severity > INFO.Severity():
+		return severityNotice
+	case severity == INFO.Severity():
+		return severityInfo
+	default:
+		return severityDebug
+	}
+}
+
+// SyslogEncoder renders entries as syslog lines that rsyslog, syslog-ng and journald accept,
+// in the RFC5424 format:
+//
+//	<12>1 2009-01-23T01:23:23.000000Z host app 42 - [fields@32473 user="alice"] name: message
+//
+// or in the RFC3164 format:
+//
+//	<12>Jan 23 01:23:23 host app[42]: name: message user=alice
+//
+// The priority is computed from the Facility and the level of the entry, see
+// syslogSeverity. The caller, the stack and the causes of the entry are not rendered and
+// the control characters of the message are escaped as #ooo, so that an entry is a single
+// line.
+type SyslogEncoder struct {
+	Format   SyslogFormat
+	Facility Facility
+	// AppName identifies the program, the name of the executable by default.
+	AppName string
+	// Hostname is the name of the host, the name reported by the kernel by default. An empty
+	// Hostname is omitted by the RFC3164 format, the local syslog daemon adds it.
+	Hostname string
+	// PID is the process ID, the ID of the current process by default, 0 omits it.
+	PID int
+}
+
+// NewSyslogEncoder returns a SyslogEncoder rendering the format with the facility and the
+// app name, the name of the executable if appName is empty.
+func NewSyslogEncoder(format SyslogFormat, facility Facility, appName string) *SyslogEncoder {
+	if appName == "" {
+		appName = filepath.Base(os.Args[0])
+	}
+	hostname, _ := os.Hostname()
+	return &SyslogEncoder{
+		Format:   format,
+		Facility: facility,
+		AppName:  appName,
+		Hostname: hostname,
+		PID:      os.Getpid(),
+	}
+}
+
+// Encode implements the Encoder interface.
+func (e *SyslogEncoder) Encode(buf []byte, entry *Entry) []byte {
+	t := entry.Time
+	if entry.Flags&LUTC != 0 {
+		t = t.UTC()
+	}
+	buf = append(buf, '<')
+	buf = strconv.AppendInt(buf, int64(e.Facility)*8+int64(syslogSeverity(entry.Level)), 10)
+	buf = append(buf, '>')
+	if e.Format == RFC3164 {
+		buf = t.AppendFormat(buf, time.Stamp)
+		buf = append(buf, ' ')
+		if e.Hostname != "" {
+			buf = appendSyslogName(buf, e.Hostname, 255)
+			buf = append(buf, ' ')
+		}
+		buf = appendSyslogName(buf, e.AppName, 32)
+		if e.PID != 0 {
+			buf = append(buf, '[')
+			buf = strconv.AppendInt(buf, int64(e.PID), 10)
+			buf = append(buf, ']')
+		}
+		buf = append(buf, ": "...)
+		buf = appendSyslogMessage(buf, entry)
+		buf = appendFields(buf, entry.Fields)
+		return append(buf, '\n')
+	}
+	buf = append(buf, "1 "...)
+	buf = t.AppendFormat(buf, rfc5424Time)
+	buf = append(buf, ' ')
+	buf = appendSyslogName(buf, e.Hostname, 255)
+	buf = append(buf, ' ')
+	buf = appendSyslogName(buf, e.AppName, 48)
+	buf = append(buf, ' ')
+	if e.PID != 0 {
+		buf = strconv.AppendInt(buf, int64(e.PID), 10)
+	} else {
+		buf = append(buf, syslogNil...)
+	}
+	// no MSGID
+	buf = append(buf, " - "...)
+	buf = appendStructuredData(buf, entry.Fields)
+	buf = append(buf, ' ')
+	buf = appendSyslogMessage(buf, entry)
+	return append(buf, '\n')
+}
+
+// appendSyslogName appends a header field of at most size characters, the characters other
+// than the printable ASCII characters are replaced by '_' and an empty name by `-`.
+func appendSyslogName(buf []byte, name string, size int) []byte {
+	if name == "" {
+		return append(buf, syslogNil...)
+	}
+	if len(name) > size {
+		name = name[:size]
+	}
+	for index := 0; index < len(name); index++ {
+		if c := name[index]; c > ' ' && c < 0x7f {
+			buf = append(buf, c)
+		} else {
+			buf = append(buf, '_')
+		}
+	}
+	return buf
+}
+
+// appendSyslogMessage appends the prefix, the logger name and the message of the entry.
+func appendSyslogMessage(buf []byte, entry *Entry) []byte {
+	buf = appendSyslogText(buf, entry.Prefix)
+	if entry.Name != "" {
+		buf = appendSyslogText(buf, entry.Name)
+		buf = append(buf, ": "...)
+	}
+	return appendSyslogText(buf, entry.Message)
+}
+
+// appendSyslogText appends s with the control characters escaped as # and their octal
+// code, like rsyslog does.
+func appendSyslogText(buf []byte, s string) []byte {
+	for index := 0; index < len(s); index++ {
+		c := s[index]
+		if c >= ' ' && c != 0x7f {
+			buf = append(buf, c)
+			continue
+		}
+		buf = append(buf, '#', '0'+c>>6, '0'+c>>3&7, '0'+c&7)
+	}
+	return buf
+}
+
+// appendStructuredData appends the fields as the structured data of the RFC5424 format,
+// `-` if there are none.
+func appendStructuredData(buf []byte, fields []Field) []byte {
+	if len(fields) == 0 {
+		return append(buf, syslogNil...)
+	}
+	buf = append(buf, '[')
+	buf = append(buf, syslogSDID...)
+	for index := range fields {
+		buf = append(buf, ' ')
+		buf = appendSDName(buf, fields[index].Key)
+		buf = append(buf, `="`...)
+		start := len(buf)
+		buf = fields[index].appendValue(buf, false)
+		if needsSDEscape(buf[start:]) {
+			value := string(buf[start:])
+			buf = buf[:start]
+			for i := 0; i < len(value); i++ {
+				if c := value[i]; c == '"' || c == '\\' || c == ']' {
+					buf = append(buf, '\\', c)
+				} else {
+					buf = appendSyslogText(buf, value[i:i+1])
+				}
+			}
+		}
+		buf = append(buf, '"')
+	}
+	return append(buf, ']')
+}
+
+// needsSDEscape reports whether the parameter value contains characters that must be
+// escaped: '"', '\\', ']' and the control characters.
+func needsSDEscape(value []byte) bool {
+	for _, c := range value {
+		if c == '"' || c == '\\' || c == ']' || c < ' ' || c == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// appendSDName appends the key of a field as a parameter name of the structured data, at
+// most 32 printable ASCII characters other than '=', ' ', ']' and '"'.
+func appendSDName(buf []byte, key string) []byte {
+	if key == "" {
+		return append(buf, '_')
+	}
+	if len(key) > 32 {
+		key = key[:32]
+	}
+	for index := 0; index < len(key); index++ {
+		c := key[index]
+		if c > ' ' && c < 0x7f && c != '=' && c != ']' && c != '"' {
+			buf = append(buf, c)
+		} else {
+			buf = append(buf, '_')
+		}
+	}
+	return buf
+}
+
+var (
+	// SyslogClosedError is returned when writing to a closed SyslogWriter.
+	SyslogClosedError = errors.Error("write to closed syslog writer")
+)
+
+// syslogSockets are the paths of the socket of the local syslog daemon on Linux, macOS and
+// the BSDs, journald also listens on /dev/log.
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogWriter is an io.Writer that sends every write as a datagram to the unix socket of
+// the local syslog daemon, it is used with a SyslogEncoder:
+//
+//	w, err := log.NewSyslogWriter("")
+//	log.SetOutput(w)
+//	log.SetEncoder(log.NewSyslogEncoder(log.RFC5424, log.FacilityLocal0, "app"))
+//
+// The connection is reopened once when a write fails, e.g. after the daemon restarted.
+// It is safe for concurrent use.
+type SyslogWriter struct {
+	path string
+	mtx  sync.Mutex
+	conn net.Conn
+}
+
+var _ io.WriteCloser = (*SyslogWriter)(nil)
+
+// NewSyslogWriter returns a SyslogWriter sending to the unix datagram socket at path, or to
+// the first socket of syslogSockets accepting the connection if path is empty.
+func NewSyslogWriter(path string) (*SyslogWriter, error) {
+	paths := syslogSockets
+	if path != "" {
+		paths = []string{path}
+	}
+	var err error
+	for _, path := range paths {
+		var conn net.Conn
+		if conn, err = net.Dial("unixgram", path); err == nil {
+			return &SyslogWriter{path: path, conn: conn}, nil
+		}
+	}
+	return nil, errors.Newf("failed to connect to syslog socket, err: %s", err)
+}
+
+// Write implements the io.Writer interface, b is sent as one datagram without its trailing
+// newline.
+func (w *SyslogWriter) Write(b []byte) (int, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.conn == nil {
+		return 0, SyslogClosedError
+	}
+	msg := b
+	if len(msg) > 0 && msg[len(msg)-1] == '\n' {
+		msg = msg[:len(msg)-1]
+	}
+	if _, err := w.conn.Write(msg); err != nil {
+		conn, e := net.Dial("unixgram", w.path)
+		if e != nil {
+			return 0, errors.Newf("failed to write to syslog socket %s, err: %s", w.path, err)
+		}
+		_ = w.conn.Close()
+		w.conn = conn
+		if _, err = w.conn.Write(msg); err != nil {
+			return 0, errors.Newf("failed to write to syslog socket %s, err: %s", w.path, err)
+		}
+	}
+	return len(b), nil
+}
+
+// Close closes the connection, the following writes fail with SyslogClosedError.
+func (w *SyslogWriter) Close() error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
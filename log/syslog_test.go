@@ -0,0 +1,157 @@
+package log
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyslogSeverity(t *testing.T) {
+	cases := []struct {
+		level Level
+		want  int
+	}{
+		{TRACE, severityDebug},
+		{DEBUG, severityDebug},
+		{INFO, severityInfo},
+		{WARN, severityWarning},
+		{ERROR, severityErr},
+		{FATAL, severityCrit},
+	}
+	for _, c := range cases {
+		t.Run(c.level.Name(), func(t *testing.T) {
+			require.Equal(t, c.want, syslogSeverity(c.level))
+		})
+	}
+}
+
+func TestSyslogEncoder(t *testing.T) {
+	at := time.Date(2009, time.January, 3, 1, 23, 23, 500, time.UTC)
+	entry := Entry{
+		Time:    at,
+		Level:   WARN,
+		Name:    "storage",
+		Caller:  Caller{File: "/a/b/c/d.go", Line: 23},
+		Message: "slow\nquery",
+		Fields: []Field{
+			{Key: "user", Value: "alice"},
+			{Key: "sql key", Value: `a="b"] \`},
+			Int("rows", 3),
+		},
+	}
+	encoder := &SyslogEncoder{Facility: FacilityLocal0, AppName: "my app", Hostname: "host", PID: 42}
+
+	t.Run("rfc5424", func(t *testing.T) {
+		require.Equal(t,
+			`<132>1 2009-01-03T01:23:23.000000Z host my_app 42 - [fields@32473 user="alice" sql_key="a=\"b\"\] \\" rows="3"] storage: slow#012query`+"\n",
+			string(encoder.Encode(nil, &entry)))
+
+		// empty header fields and no fields
+		plain := Entry{Time: at, Level: FATAL, Message: "bye"}
+		empty := &SyslogEncoder{Facility: FacilityDaemon}
+		require.Equal(t, "<26>1 2009-01-03T01:23:23.000000Z - - - - - bye\n", string(empty.Encode(nil, &plain)))
+	})
+
+	t.Run("rfc3164", func(t *testing.T) {
+		encoder := *encoder
+		encoder.Format = RFC3164
+		require.Equal(t,
+			`<132>Jan  3 01:23:23 host my_app[42]: storage: slow#012query user=alice "sql key"="a=\"b\"] \\" rows=3`+"\n",
+			string(encoder.Encode(nil, &entry)))
+
+		// the local daemon adds the hostname
+		encoder.Hostname = ""
+		encoder.PID = 0
+		plain := Entry{Time: at, Level: DEBUG, Message: "query", Prefix: "db "}
+		require.Equal(t, "<135>Jan  3 01:23:23 my_app: db query\n", string(encoder.Encode(nil, &plain)))
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		encoder := NewSyslogEncoder(RFC3164, FacilityUser, "")
+		require.Equal(t, filepath.Base(os.Args[0]), encoder.AppName)
+		require.Equal(t, os.Getpid(), encoder.PID)
+		hostname, _ := os.Hostname()
+		require.Equal(t, hostname, encoder.Hostname)
+	})
+}
+
+// listenSyslog listens on a unix datagram socket in a temporary directory.
+func listenSyslog(t *testing.T, path string) *net.UnixConn {
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn
+}
+
+// readDatagram reads a datagram from the listener.
+func readDatagram(t *testing.T, conn *net.UnixConn) string {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
+
+func TestSyslogWriter(t *testing.T) {
+	// the path of a unix socket is limited to about 100 bytes, t.TempDir may be too long
+	folder, err := os.MkdirTemp("", "syslog")
+	require.NoError(t, err)
+	defer os.RemoveAll(folder)
+	path := filepath.Join(folder, "log.sock")
+	listener := listenSyslog(t, path)
+
+	_, err = NewSyslogWriter(filepath.Join(folder, "missing.sock"))
+	require.Error(t, err)
+
+	writer, err := NewSyslogWriter(path)
+	require.NoError(t, err)
+	defer SetOutput(os.Stdout)
+	defer SetEncoder(NewTextEncoder())
+	SetOutput(writer)
+	SetEncoder(&SyslogEncoder{Facility: FacilityLocal7, AppName: "app", PID: 7})
+	SetLevel(INFO)
+	SetFlags(0)
+
+	Warnw("disk almost full", "free", "1%")
+	got := readDatagram(t, listener)
+	require.Regexp(t, `^<188>1 \S+ - app 7 - \[fields@32473 free="1%"\] disk almost full$`, got)
+
+	// the writer reconnects to a restarted daemon
+	require.NoError(t, listener.Close())
+	require.NoError(t, os.Remove(path))
+	listener = listenSyslog(t, path)
+	Info("restarted")
+	require.Regexp(t, `^<190>1 .* restarted$`, readDatagram(t, listener))
+
+	require.NoError(t, writer.Close())
+	require.NoError(t, writer.Close())
+	_, err = writer.Write([]byte("closed\n"))
+	require.ErrorIs(t, err, SyslogClosedError)
+}
+
+func TestSyslogWriterDefault(t *testing.T) {
+	origin := syslogSockets
+	defer func() {
+		syslogSockets = origin
+	}()
+	folder, err := os.MkdirTemp("", "syslog")
+	require.NoError(t, err)
+	defer os.RemoveAll(folder)
+	path := filepath.Join(folder, "dev-log")
+	listener := listenSyslog(t, path)
+	syslogSockets = []string{filepath.Join(folder, "missing"), path}
+
+	writer, err := NewSyslogWriter("")
+	require.NoError(t, err)
+	defer writer.Close()
+	n, err := writer.Write([]byte("<14>message 1\n"))
+	require.NoError(t, err)
+	require.Equal(t, 14, n)
+	require.Equal(t, "<14>message 1", readDatagram(t, listener))
+}
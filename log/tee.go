@@ -0,0 +1,106 @@
+package log
+
+import (
+	"io"
+	"sync"
+
+	"github.com/stkali/utility/errors"
+)
+
+// EntryWriter is implemented by outputs that render entries themselves, such as Tee.
+// When the output of a logger implements EntryWriter, the logger hands it every entry
+// together with the encoder of the logger instead of writing the encoded bytes.
+type EntryWriter interface {
+	WriteEntry(entry *Entry, encoder Encoder) error
+}
+
+// Sink is a destination of a Tee.
+type Sink struct {
+	// Writer is the destination of the entries.
+	Writer io.Writer
+	// Level is the minimum level of the entries written to the sink.
+	Level Level
+	// Encoder renders the entries written to the sink, nil means the encoder of the logger.
+	Encoder Encoder
+}
+
+// Tee is an output that fans entries out to multiple sinks, each with its own minimum
+// level and encoder. A failing sink is reported by errors.Warning and does not prevent
+// the other sinks from being written.
+//
+// The level of the logger still applies first, so it must be lower than or equal to the
+// lowest level of the sinks.
+type Tee struct {
+	sinks []Sink
+	mtx   sync.Mutex
+}
+
+var _ EntryWriter = (*Tee)(nil)
+var _ io.Writer = (*Tee)(nil)
+var _ Flusher = (*Tee)(nil)
+
+// NewTee returns a Tee writing to the sinks, sinks without Writer are ignored.
+func NewTee(sinks ...Sink) *Tee {
+	t := &Tee{sinks: make([]Sink, 0, len(sinks))}
+	for _, sink := range sinks {
+		if sink.Writer != nil {
+			t.sinks = append(t.sinks, sink)
+		}
+	}
+	return t
+}
+
+// WriteEntry implements the EntryWriter interface. It writes the entry to the sinks whose
+// level is lower than or equal to the level of the entry and returns the errors of the
+// failed sinks joined.
+func (t *Tee) WriteEntry(entry *Entry, encoder Encoder) error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	buf := getBuffer()
+	defer putBuffer(buf)
+	var err error
+	for index := range t.sinks {
+		sink := &t.sinks[index]
+		if !atLeast(entry.Level, sink.Level) {
+			continue
+		}
+		enc := sink.Encoder
+		if enc == nil {
+			enc = encoder
+		}
+		*buf = enc.Encode((*buf)[:0], entry)
+		if _, e := sink.Writer.Write(*buf); e != nil {
+			errors.Warningf("failed to write log entry to sink %d, err: %s", index, e)
+			err = errors.Join(err, e)
+		}
+	}
+	return err
+}
+
+// Write implements the io.Writer interface, it writes b to every sink regardless of its
+// level and encoder.
+func (t *Tee) Write(b []byte) (int, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	var err error
+	for index := range t.sinks {
+		if _, e := t.sinks[index].Writer.Write(b); e != nil {
+			errors.Warningf("failed to write log to sink %d, err: %s", index, e)
+			err = errors.Join(err, e)
+		}
+	}
+	return len(b), err
+}
+
+// Flush implements the Flusher interface, it flushes the sinks implementing Flusher.
+func (t *Tee) Flush() error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	var err error
+	for index := range t.sinks {
+		if flusher, ok := t.sinks[index].Writer.(Flusher); ok {
+			err = errors.Join(err, flusher.Flush())
+		}
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/stkali/utility/errors"
+)
+
+// failWriter fails every write.
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestTee(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	file := &bytes.Buffer{}
+	warnings := &bytes.Buffer{}
+	errors.SetWarningOutput(warnings)
+	defer errors.SetWarningOutput(os.Stderr)
+
+	tee := NewTee(
+		Sink{Writer: stderr, Level: ERROR},
+		Sink{Writer: failWriter{}, Level: TRACE},
+		Sink{Writer: file, Level: DEBUG, Encoder: NewJSONEncoder()},
+		Sink{Level: TRACE},
+	)
+	require.Equal(t, 3, len(tee.sinks))
+
+	SetOutput(tee)
+	SetLevel(TRACE)
+	SetFlags(0)
+	SetPrefix("")
+	defer SetOutput(os.Stdout)
+
+	Trace("trace")
+	require.Equal(t, 0, stderr.Len())
+	require.Equal(t, 0, file.Len())
+
+	Debugw("debug", "k", "v")
+	require.Equal(t, 0, stderr.Len())
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(file.Bytes(), &got))
+	require.Equal(t, "debug", got["msg"])
+	require.Equal(t, "v", got["k"])
+	// the caller is resolved for the JSON sink even though the logger has no file flag
+	require.True(t, strings.HasPrefix(got["caller"].(string), "tee_test.go:"))
+
+	file.Reset()
+	Error("failed")
+	require.Equal(t, ERROR.String()+"failed\n", stderr.String())
+	require.Contains(t, file.String(), `"msg":"failed"`)
+	require.Contains(t, warnings.String(), "failed to write log entry to sink 1")
+
+	// raw bytes are written to every sink
+	stderr.Reset()
+	file.Reset()
+	n, err := tee.Write([]byte("raw\n"))
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+	require.Equal(t, 4, n)
+	require.Equal(t, "raw\n", stderr.String())
+	require.Equal(t, "raw\n", file.String())
+	require.Contains(t, warnings.String(), "failed to write log to sink 1")
+}
+
+func TestTeeFlush(t *testing.T) {
+	slow := newSlowWriter()
+	close(slow.release)
+	async := NewAsyncWriter(slow, 4, Block)
+	defer async.Close()
+	tee := NewTee(Sink{Writer: async}, Sink{Writer: io.Discard})
+	require.NoError(t, tee.WriteEntry(&Entry{Level: INFO, Message: "queued"}, NewTextEncoder()))
+	require.NoError(t, tee.Flush())
+	require.Equal(t, INFO.String()+"queued\n", slow.String())
+}
@@ -0,0 +1,8 @@
+//go:build linux
+
+package log
+
+import "syscall"
+
+// ioctlReadTermios is the ioctl reading the settings of a terminal.
+const ioctlReadTermios = syscall.TCGETS
@@ -0,0 +1,11 @@
+//go:build windows
+
+package log
+
+import "syscall"
+
+// isTerminalFd reports whether fd is a console, only consoles have a console mode.
+func isTerminalFd(fd uintptr) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(fd), &mode) == nil
+}
@@ -0,0 +1,124 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/stkali/utility/errors"
+)
+
+// maxCauseDepth limits the depth of the rendered cause tree.
+const maxCauseDepth = 16
+
+// Traceback configures the rendering of the errors logged as arguments or field values.
+// Once set, errors in the message are rendered by their message only, and for the first
+// error of an entry, the stack trace captured by errors.Newf (any error
+// implementing errors.Tracer in the chain) is rendered on entries at or above StackLevel,
+// and the tree of wrapped errors on entries at or above CauseLevel.
+type Traceback struct {
+	StackLevel Level
+	CauseLevel Level
+}
+
+// plainError hides the fmt.Formatter of the wrapped error so that `%v` renders the error
+// message only, the stack trace is rendered by the Traceback settings instead.
+type plainError struct {
+	error
+}
+
+// plainErrors returns a copy of args where the errors are wrapped in plainError, args is
+// returned if it has no error.
+func plainErrors(args []any) []any {
+	var values []any
+	for index, arg := range args {
+		if err, ok := arg.(error); ok {
+			if values == nil {
+				values = make([]any, len(args))
+				copy(values, args)
+			}
+			values[index] = plainError{err}
+		}
+	}
+	if values == nil {
+		return args
+	}
+	return values
+}
+
+// findError returns the first error of values.
+func findError(values []any) error {
+	for _, value := range values {
+		switch v := value.(type) {
+		case error:
+			return v
+		case Field:
+			if err, ok := v.Value.(error); ok {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// findFieldError returns the value of the first field holding an error.
+func findFieldError(fields []Field) error {
+	for index := range fields {
+		if err, ok := fields[index].Value.(error); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// render fills the Stack and Causes of the entry with the trace of err.
+func (t *Traceback) render(entry *Entry, err error) {
+	if err == nil {
+		return
+	}
+	if atLeast(entry.Level, t.StackLevel) {
+		var tracer errors.Tracer
+		if errors.As(err, &tracer) {
+			buf := &bytes.Buffer{}
+			tracer.Traceback(buf)
+			entry.Stack = buf.String()
+		}
+	}
+	if atLeast(entry.Level, t.CauseLevel) {
+		entry.Causes = appendCauses(nil, err, 0)
+	}
+}
+
+// unwrap returns the errors wrapped by err.
+func unwrap(err error) []error {
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		return e.Unwrap()
+	case interface{ Unwrap() error }:
+		if cause := e.Unwrap(); cause != nil {
+			return []error{cause}
+		}
+	}
+	return nil
+}
+
+// appendCauses appends the tree of the errors wrapped by err to causes, one error per line
+// indented by its depth. Wrapped errors with the same message as their parent are not
+// rendered but their own causes are.
+func appendCauses(causes []string, err error, depth int) []string {
+	if depth >= maxCauseDepth {
+		return causes
+	}
+	msg := err.Error()
+	for _, cause := range unwrap(err) {
+		if cause == nil {
+			continue
+		}
+		if text := cause.Error(); text != msg {
+			causes = append(causes, strings.Repeat("    ", depth)+text)
+			causes = appendCauses(causes, cause, depth+1)
+		} else {
+			causes = appendCauses(causes, cause, depth)
+		}
+	}
+	return causes
+}
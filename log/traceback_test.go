@@ -0,0 +1,100 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stkali/utility/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindError(t *testing.T) {
+	err := errors.Error("boom")
+	require.Nil(t, findError(nil))
+	require.Nil(t, findError([]any{"a", 1}))
+	require.Equal(t, err, findError([]any{"a", err, errors.Error("other")}))
+	require.Equal(t, err, findError([]any{"key", "value", Field{Key: "err", Value: err}}))
+}
+
+func TestAppendCauses(t *testing.T) {
+	_, openErr := os.Open("not-exists.txt")
+	require.Error(t, openErr)
+	err := errors.Newf("failed to load config: %s", openErr)
+	require.Equal(t, []string{
+		openErr.Error(),
+		"    " + errors.Unwrap(openErr).Error(),
+	}, appendCauses(nil, err, 0))
+
+	joined := errors.Join(errors.New("first"), errors.New("second"))
+	require.Equal(t, []string{"first", "second"}, appendCauses(nil, joined, 0))
+
+	require.Nil(t, appendCauses(nil, errors.New("leaf"), 0))
+}
+
+func TestTraceback(t *testing.T) {
+	recorder := new(bytes.Buffer)
+	SetOutput(recorder)
+	SetLevel(TRACE)
+	SetFlags(0)
+	SetPrefix("")
+	defer func() {
+		SetTraceback(nil)
+		SetEncoder(nil)
+		SetLevel(defaultLevel)
+		SetOutput(os.Stdout)
+	}()
+
+	cause := errors.New("disk full")
+	err := errors.Newf("failed to save: %s", cause)
+
+	t.Run("disabled", func(t *testing.T) {
+		// errors are rendered by their fmt.Formatter
+		recorder.Reset()
+		Error(err)
+		require.True(t, strings.HasPrefix(recorder.String(), ERROR.String()+"Error: failed to save: disk full\nTraceback:\n"))
+	})
+
+	SetTraceback(&Traceback{StackLevel: ERROR, CauseLevel: WARN})
+
+	t.Run("below levels", func(t *testing.T) {
+		recorder.Reset()
+		Info(err)
+		require.Equal(t, INFO.String()+"failed to save: disk full\n", recorder.String())
+	})
+
+	t.Run("causes only", func(t *testing.T) {
+		recorder.Reset()
+		Warnw("retry", "err", err)
+		require.Equal(t, WARN.String()+"retry err=\"failed to save: disk full\"\nCauses:\n    disk full\n",
+			recorder.String())
+	})
+
+	t.Run("stack and causes", func(t *testing.T) {
+		recorder.Reset()
+		Errorf("save: %s", err)
+		output := recorder.String()
+		require.True(t, strings.HasPrefix(output, ERROR.String()+"save: failed to save: disk full\nCauses:\n    disk full\nTraceback:\n"), output)
+		require.Contains(t, output, "log.TestTraceback")
+		require.Contains(t, output, "traceback_test.go")
+	})
+
+	t.Run("without tracer", func(t *testing.T) {
+		recorder.Reset()
+		Error(cause)
+		require.Equal(t, ERROR.String()+"disk full\n", recorder.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		SetEncoder(NewJSONEncoder())
+		defer SetEncoder(nil)
+		recorder.Reset()
+		Fatal(err)
+		record := map[string]any{}
+		require.NoError(t, json.Unmarshal(recorder.Bytes(), &record))
+		require.Equal(t, []any{"disk full"}, record["causes"])
+		require.Contains(t, record["stack"], "Traceback:\n")
+	})
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin
+
+package rotate
+
+import (
+	"syscall"
+)
+
+// freeSpace returns the space available to an unprivileged user on the disk of folder.
+func freeSpace(folder string) (int64, error) {
+	st := &syscall.Statfs_t{}
+	if err := syscall.Statfs(folder, st); err != nil {
+		return 0, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize), nil
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package rotate
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// freeSpace returns the space available to the user on the disk of folder.
+func freeSpace(folder string) (int64, error) {
+	path, err := syscall.UTF16PtrFromString(folder)
+	if err != nil {
+		return 0, err
+	}
+	var available int64
+	ret, _, err := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(&available)), 0, 0)
+	if ret == 0 {
+		return 0, err
+	}
+	return available, nil
+}
@@ -1,4 +1,4 @@
-package rotate
+package rotate_test
 
 import (
 	"github.com/stkali/utility/errors"
@@ -0,0 +1,142 @@
+// Copyright 2021-2024 The utility Authors. All rights reserved.
+// Use of this source code is governed by the MIT license that can be found in the
+// LICENSE file
+
+package rotate
+
+import (
+	"os"
+	"sync"
+
+	"github.com/stkali/utility/errors"
+)
+
+// The reasons of the deletions of the backup files, in the order of the strategies.
+const (
+	// BackupsReason deletes the backup files beyond Backups.
+	BackupsReason = "backups"
+	// MaxAgeReason deletes the backup files older than MaxAge.
+	MaxAgeReason = "max age"
+	// MaxTotalSizeReason deletes the backup files that do not fit in MaxTotalSize.
+	MaxTotalSizeReason = "max total size"
+	// MinFreeSpaceReason deletes the backup files until MinFreeSpace is available.
+	MinFreeSpaceReason = "min free space"
+)
+
+// Observer is notified of the lifecycle of the backup files of a rotating file, e.g. to
+// ship them to an archive or to count the deletions.
+//
+// The methods are called by the cleanup goroutine without holding the mutex of the
+// rotating file, one at a time and in order. They block the cleanup, and must not write to
+// the rotating file, whose Close waits for the cleanup goroutine.
+type Observer interface {
+	// OnRotate is called after the rotating file old was renamed to the backup file.
+	OnRotate(old, backup string)
+	// OnCompressed is called after the backup file src was compressed to dst, ratio is the
+	// size of dst divided by the size of src, 0 if src is empty.
+	OnCompressed(src, dst string, ratio float64)
+	// OnDeleted is called after the backup file was deleted, the reason is one of
+	// BackupsReason, MaxAgeReason, MaxTotalSizeReason and MinFreeSpaceReason.
+	OnDeleted(file, reason string)
+	// OnError is called when the operation op fails, op is "rotate", "clean", "compress"
+	// or "delete". The failed rotations are notified in order with the other rotations.
+	OnError(op string, err error)
+}
+
+// rotation is a rotation waiting to be notified to the observer, err is set if it failed.
+type rotation struct {
+	old, backup string
+	err         error
+}
+
+// rotations queues the rotations under the mutex of the rotating file, until the cleanup
+// goroutine notifies them without it.
+type rotations struct {
+	mtx   sync.Mutex
+	queue []rotation
+}
+
+// push queues the rotation of old to backup.
+func (q *rotations) push(old, backup string) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	q.queue = append(q.queue, rotation{old: old, backup: backup})
+}
+
+// pushError queues the failed rotation.
+func (q *rotations) pushError(err error) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	q.queue = append(q.queue, rotation{err: err})
+}
+
+// pop removes and returns the queued rotations.
+func (q *rotations) pop() []rotation {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	queue := q.queue
+	q.queue = nil
+	return queue
+}
+
+// pending reports whether a rotation is queued.
+func (q *rotations) pending() bool {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	return len(q.queue) > 0
+}
+
+// notifyRotations notifies the observer of the queued rotations.
+func (r *RotatingFile) notifyRotations() {
+	for _, rt := range r.rotations.pop() {
+		if rt.err != nil {
+			r.option.Observer.OnError("rotate", rt.err)
+		} else {
+			r.option.Observer.OnRotate(rt.old, rt.backup)
+		}
+	}
+}
+
+// notifyError notifies the observer of the failed operation op, if any.
+func (r *RotatingFile) notifyError(op string, err error) {
+	if err != nil && r.option.Observer != nil {
+		r.option.Observer.OnError(op, err)
+	}
+}
+
+// deleteBackupFiles deletes the specified backup files for the reason.
+// It prints a warning if any deletion fails.
+func (r *RotatingFile) deleteBackupFiles(files []backupFile, reason string) {
+	for index := range files {
+		if err := deleteFile(files[index].file); err != nil {
+			r.notifyError("delete", err)
+		} else if r.option.Observer != nil {
+			r.option.Observer.OnDeleted(files[index].file, reason)
+		}
+	}
+}
+
+// compressBackupFile compresses the backup file src to src.gz.
+// It prints a warning if the compression fails.
+func (r *RotatingFile) compressBackupFile(src string) {
+	dst := src + compressExtension
+	// the size of src is only needed by the observer
+	var srcInfo os.FileInfo
+	if r.option.Observer != nil {
+		srcInfo, _ = osStat(src)
+	}
+	err := compressFile(src, dst, r.option.CompressLevel)
+	if err != nil {
+		errors.Warning(err)
+		r.notifyError("compress", err)
+		return
+	}
+	if r.option.Observer == nil {
+		return
+	}
+	var ratio float64
+	if dstInfo, err := osStat(dst); err == nil && srcInfo != nil && srcInfo.Size() > 0 {
+		ratio = float64(dstInfo.Size()) / float64(srcInfo.Size())
+	}
+	r.option.Observer.OnCompressed(src, dst, ratio)
+}
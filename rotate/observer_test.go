@@ -0,0 +1,219 @@
+package rotate
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stkali/utility/lib"
+	"github.com/stretchr/testify/require"
+)
+
+// event is a notification received by the recordingObserver, the fields not used by the
+// method are empty.
+type event struct {
+	method string
+	file   string
+	target string
+	reason string
+	err    error
+}
+
+// recordingObserver records the notifications of a rotating file.
+type recordingObserver struct {
+	mtx    sync.Mutex
+	events []event
+}
+
+func (o *recordingObserver) OnRotate(old, backup string) {
+	o.record(event{method: "rotate", file: old, target: backup})
+}
+
+func (o *recordingObserver) OnCompressed(src, dst string, ratio float64) {
+	if ratio > 0 {
+		o.record(event{method: "compressed", file: src, target: dst})
+	}
+}
+
+func (o *recordingObserver) OnDeleted(file, reason string) {
+	o.record(event{method: "deleted", file: file, reason: reason})
+}
+
+func (o *recordingObserver) OnError(op string, err error) {
+	o.record(event{method: "error", reason: op, err: err})
+}
+
+func (o *recordingObserver) record(e event) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	o.events = append(o.events, e)
+}
+
+// pop removes and returns the recorded notifications.
+func (o *recordingObserver) pop() []event {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	events := o.events
+	o.events = nil
+	return events
+}
+
+func TestObserver(t *testing.T) {
+	testDir := t.TempDir()
+	testFile := filepath.Join(testDir, "app.log")
+	observer := &recordingObserver{}
+	f, err := NewRotatingFile(testFile,
+		WithMaxSize(10),
+		WithDuration(-1),
+		WithBackups(1),
+		WithObserver(observer),
+	)
+	require.NoError(t, err)
+	defer f.Close()
+
+	t.Run("rotate and compress", func(t *testing.T) {
+		_, err = f.WriteString("hello world!")
+		require.NoError(t, err)
+		f.waitTidy()
+		events := observer.pop()
+		require.Equal(t, 2, len(events))
+		require.Equal(t, "rotate", events[0].method)
+		require.Equal(t, testFile, events[0].file)
+		backup := events[0].target
+		require.Equal(t, testDir, filepath.Dir(backup))
+		require.Equal(t, event{method: "compressed", file: backup, target: backup + compressExtension}, events[1])
+		require.FileExists(t, backup+compressExtension)
+
+		// the previous backup is deleted by Backups
+		_, err = f.WriteString("hello world!")
+		require.NoError(t, err)
+		f.waitTidy()
+		events = observer.pop()
+		require.Equal(t, 3, len(events))
+		require.Equal(t, "rotate", events[0].method)
+		require.Equal(t, event{method: "deleted", file: backup + compressExtension, reason: BackupsReason}, events[1])
+		require.Equal(t, "compressed", events[2].method)
+		require.Equal(t, events[0].target, events[2].file)
+	})
+
+	t.Run("delete reasons", func(t *testing.T) {
+		f.option.Backups = 4
+		f.option.MaxAge = 150 * time.Minute
+		f.option.MaxTotalSize = 150
+		defer func() {
+			f.option.Backups = 1
+			f.option.MaxAge = lib.Month
+			f.option.MaxTotalSize = 0
+		}()
+		observer.pop()
+		require.NoError(t, os.RemoveAll(testDir))
+		require.NoError(t, os.MkdirAll(testDir, 0o755))
+		files := make([]string, 0, 6)
+		for i := 0; i < cap(files); i++ {
+			file := filepath.Join(testDir, f.nextBackupFilename())
+			require.NoError(t, os.WriteFile(file, make([]byte, 100), 0o644))
+			modTime := time.Now().Add(time.Duration(i-cap(files)) * time.Hour)
+			require.NoError(t, os.Chtimes(file, modTime, modTime))
+			files = append(files, file)
+		}
+		bks, err := f.cleanBackups()
+		require.NoError(t, err)
+		require.Equal(t, 1, len(bks))
+		require.Equal(t, files[5], bks[0].file)
+		require.Equal(t, []event{
+			{method: "deleted", file: files[0], reason: BackupsReason},
+			{method: "deleted", file: files[1], reason: BackupsReason},
+			{method: "deleted", file: files[2], reason: MaxAgeReason},
+			{method: "deleted", file: files[3], reason: MaxAgeReason},
+			{method: "deleted", file: files[4], reason: MaxTotalSizeReason},
+		}, observer.pop())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		osReadDir = func(name string) ([]os.DirEntry, error) {
+			return nil, os.ErrInvalid
+		}
+		f.tidyBackups()
+		f.waitTidy()
+		osReadDir = os.ReadDir
+		events := observer.pop()
+		require.Equal(t, 1, len(events))
+		require.Equal(t, "clean", events[0].reason)
+		require.ErrorIs(t, events[0].err, os.ErrInvalid)
+
+		osRemove = func(name string) error {
+			return os.ErrPermission
+		}
+		defer func() {
+			osRemove = os.Remove
+		}()
+		f.deleteBackupFiles([]backupFile{{file: testFile}}, MaxAgeReason)
+		require.Equal(t, []event{{method: "error", reason: "delete", err: os.ErrPermission}}, observer.pop())
+		osRemove = os.Remove
+
+		// the unreadable backup file
+		osOpen = func(name string) (*os.File, error) {
+			return nil, os.ErrPermission
+		}
+		defer func() {
+			osOpen = os.Open
+		}()
+		f.compressBackupFile(testFile)
+		events = observer.pop()
+		require.Equal(t, 1, len(events))
+		require.Equal(t, "compress", events[0].reason)
+		require.ErrorIs(t, events[0].err, os.ErrPermission)
+		osOpen = os.Open
+
+		// the failed rotations, by size and by time
+		osRename = func(oldpath, newpath string) error {
+			return os.ErrPermission
+		}
+		defer func() {
+			osRename = os.Rename
+		}()
+		_, err = f.WriteString("hello world!")
+		require.ErrorIs(t, err, os.ErrPermission)
+		f.waitTidy()
+		events = observer.pop()
+		require.Equal(t, "error", events[0].method)
+		require.Equal(t, "rotate", events[0].reason)
+		require.ErrorIs(t, events[0].err, os.ErrPermission)
+
+		_, err = f.WriteString("hello")
+		require.NoError(t, err)
+		f.scheduledRotate(f.nextRotatingTime)
+		f.waitTidy()
+		events = observer.pop()
+		require.Equal(t, "rotate", events[0].reason)
+		require.ErrorIs(t, events[0].err, os.ErrPermission)
+	})
+	t.Run("close waits for the restarted cleanup", func(t *testing.T) {
+		testFile := filepath.Join(t.TempDir(), "close.log")
+		observer := &recordingObserver{}
+		f, err := NewRotatingFile(testFile, WithMaxSize(10), WithDuration(0), WithCompressLevel(0),
+			WithObserver(observer))
+		require.NoError(t, err)
+
+		// the rotation during a running cleanup goroutine is notified by the goroutine
+		// restarted when it finishes
+		f.mtx.Lock()
+		require.True(t, atomic.CompareAndSwapUint32(&f.cleaning, noCleaning, cleaning))
+		tidied := make(chan struct{})
+		f.tidied.Store(tidied)
+		f.mtx.Unlock()
+		_, err = f.WriteString("hello world!")
+		require.NoError(t, err)
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			f.finishTidy(tidied)
+		}()
+		require.NoError(t, f.Close())
+		events := observer.pop()
+		require.Equal(t, 1, len(events))
+		require.Equal(t, "rotate", events[0].method)
+	})
+}
@@ -38,15 +38,22 @@ var (
 	ModePermissionError          = errors.Error("invalid mode permission")
 	InvalidBackupPrefixError     = errors.Error("invalid backup prefix")
 	InvalidCompressionLevelError = errors.Error("invalid compression level")
+	InvalidScheduleError         = errors.Error("invalid schedule")
+	InvalidLocationError         = errors.Error("invalid location")
+	InvalidBackupTemplateError   = errors.Error("invalid backup template")
+	InvalidSizeError             = errors.Error("invalid size")
 
 	// for testing, we override the default functions used by the package.
 	osOpen     = os.Open
 	osOpenFile = os.OpenFile
 	osRemove   = os.Remove
 	osRename   = os.Rename
-	osReadDir  = os.ReadDir
-	osMkdirAll = os.MkdirAll
-	ioCopy     = io.Copy
+	osStat     = os.Stat
+	// diskFreeSpace returns the free space of the disk of a folder.
+	diskFreeSpace = freeSpace
+	osReadDir     = os.ReadDir
+	osMkdirAll    = os.MkdirAll
+	ioCopy        = io.Copy
 )
 
 // Option is a configuration option for rotating files. default is `defaultOption`
@@ -64,6 +71,15 @@ type Option struct {
 	//   The timer is updated every time rotating, and this includes size-based updates
 	Duration time.Duration
 
+	// Schedule(default: nil) aligns the time-based rotation to the wall clock, e.g. Daily
+	// rotates at midnight whenever the file was opened or rotated by size.
+	// It replaces Duration when set.
+	Schedule Schedule
+
+	// Location(default: time.Local) is the time zone of Schedule, e.g. time.UTC rotates
+	// at the UTC midnight with Daily.
+	Location *time.Location
+
 	// MaxAge(default: 30 days) is the maximum age that a backup file can have before
 	// it is considered for cleanup.
 	// Files older than this duration will be deleted during the cleanup goroutine.
@@ -71,6 +87,18 @@ type Option struct {
 	// < 0 the backup deletion strategy based on `MaxAge` will not work.
 	MaxAge time.Duration
 
+	// MaxTotalSize(default: 0) is the maximum total size of the backup files, compressed
+	// or not, and the current rotating file. The oldest backup files are deleted until
+	// they fit during the cleanup goroutine.
+	// <= 0 means no limit on the total size.
+	MaxTotalSize int64
+
+	// MinFreeSpace(default: 0) is the minimum free space of the disk of the rotating file.
+	// The oldest backup files are deleted until it is available during the cleanup
+	// goroutine.
+	// <= 0 means no limit on the free space.
+	MinFreeSpace int64
+
 	// ModePerm(default: 0o644) is the default file permission bits used when
 	// creating new rotating files.
 	ModePerm os.FileMode
@@ -89,6 +117,24 @@ type Option struct {
 
 	// BackupPrefix(default: "rotating-") is the prefix to use when creating backup files.
 	BackupPrefix string
+
+	// BackupTemplate(default: "") names the backup files, e.g. TimestampTemplate or
+	// LogrotateTemplate, the backups are then ordered by the rotation time or the index
+	// parsed from their names instead of their modification time.
+	// The placeholders are:
+	//   {prefix} the BackupPrefix,
+	//   {name}   the name of the rotating file without extension,
+	//   {ext}    the extension of the rotating file,
+	//   {time}   the rotation time in Location, {time:layout} formats it with the layout,
+	//   {seq}    the sequence of the backups rotated at the same time, omitted for the first,
+	//   {index}  the index of the backup shifted at every rotation, 1 is the newest.
+	// A template must contain either {time} and {seq}, or {index}.
+	// "" names the backups `<BackupPrefix><random>-<filename>`.
+	BackupTemplate string
+
+	// Observer(default: nil) is notified of the rotations, the compressions and the
+	// deletions of the backup files, and of the errors of the cleanup goroutine.
+	Observer Observer
 }
 
 var defaultOption = &Option{
@@ -98,6 +144,7 @@ var defaultOption = &Option{
 	MaxAge:       lib.Month,
 	ModePerm:     0o644,
 	BackupPrefix: "rotating-",
+	Location:     time.Local,
 	// Available compression levels are 1-9, 9 is highest compression.
 	// I think 6 is a good compromise between speed and compression ratio.
 	CompressLevel: 6,
@@ -110,8 +157,13 @@ func (o *Option) clone() *Option {
 }
 
 type backupFile struct {
-	// modTime is the modification time of the backup file.
+	// modTime is the modification time of the backup file, or the rotation time parsed
+	// from its name.
 	modTime time.Time
+	// seq is the sequence or the index parsed from the name of the backup file.
+	seq int
+	// size is the size of the backup file.
+	size int64
 	// file is abs path of the backup file.
 	file string
 }
@@ -122,31 +174,23 @@ func (b backupFile) String() string {
 }
 
 // deleteFile deletes the specified file.
-// It prints a warning if the deletion fails.
-func deleteFile(file string) {
+// It prints a warning and returns the error if the deletion fails.
+func deleteFile(file string) error {
 	err := osRemove(file)
 	if err != nil {
 		errors.Warningf("failed to remove file %q, err: %s", file, err)
 	}
-}
-
-// deleteBackupFiles deletes the specified backup files.
-// It prints a warning if any deletion fails.
-func deleteBackupFiles(files []backupFile) {
-	for index := range files {
-		deleteFile(files[index].file)
-	}
+	return err
 }
 
 // compressFile uses gzip to compress the specified file and delete the original file.
-// If compression or deletion fails, it prints a warning and retains the source file
-// as much as possible
+// If compression fails, it returns the error and retains the source file as much as
+// possible, if deletion fails, it prints a warning.
 func compressFile(src, dst string, level int) (err error) {
 
 	f, err := osOpen(src)
 	if err != nil {
-		errors.Warningf("failed to read source file %q, err: %s", src, err)
-		return nil
+		return errors.Newf("failed to read source file %q, err: %s", src, err)
 	}
 
 	defer func() {
@@ -209,16 +253,30 @@ type RotatingFile struct {
 	folder string
 	// filename is the name of the rotating file with extension.
 	filename string
+	// template names the backup files, nil if BackupTemplate is empty.
+	template *backupTemplate
 
-	// timer is the timer that triggers the rotating rotation based on the duration interval.
-	// It is reset when a new rotating file is created.
-	timer        *time.Timer
-	rotatingTime time.Time
+	// rotatingTime is the time of the last rotation and nextRotatingTime is the time of the
+	// next time-based rotation, zero if it is disabled. The file is scheduled at
+	// nextRotatingTime by the rotationScheduler while the writer is open, and rescheduled
+	// when a new rotating file is created.
+	rotatingTime     time.Time
+	nextRotatingTime time.Time
+	scheduled        scheduledRotation
 
 	// cleaning (using an underscore prefix to avoid accidental use as a public field)
 	// is an atomic.Bool that indicates whether a garbage collection (cleanup) task
 	// is currently being executed.
 	cleaning uint32
+	// tidied holds the channel closed when the running cleanup goroutine finishes.
+	tidied atomic.Value
+
+	// rotationPending reports whether a rotation shifting the backup files was postponed
+	// until the cleanup goroutine finishes, see rotate.
+	rotationPending bool
+
+	// rotations are the rotations waiting to be notified to the Observer.
+	rotations rotations
 }
 
 // String implements the Stringer interface for RotatingFile.
@@ -242,6 +300,10 @@ func (r *RotatingFile) String() string {
 // in practice, we usually don't want this to happen. Therefore, we choose to make the
 // determination after the write so that at least one super-massive write can be performed,
 // both to avoid unnecessary errors and for more extreme cases.
+//
+// With LogrotateTemplate, a rotation while the cleanup goroutine runs is postponed until it
+// finishes, the file keeps growing past MaxSize meanwhile, so that the writes never wait for
+// the compression or the Observer.
 func (r *RotatingFile) Write(b []byte) (int, error) {
 
 	r.mtx.Lock()
@@ -278,24 +340,24 @@ func (r *RotatingFile) WriteString(s string) (int, error) {
 // It closes the rotating file and releases any associated resources.
 func (r *RotatingFile) Close() error {
 	r.mtx.Lock()
-	defer r.mtx.Unlock()
 	// close the current writer
 	err := r.close()
+	r.mtx.Unlock()
 	if err != nil {
 		return err
 	}
-	// wait for the cleanup goroutine to finish
-	for atomic.LoadUint32(&r.cleaning) == cleaning {
-	}
+	// wait for the cleanup goroutine to finish, without the mutex that finishTidy takes
+	r.waitTidy()
 	// ensure backup files is tidied up
+	r.mtx.Lock()
 	r.tidyBackups()
-	for atomic.LoadUint32(&r.cleaning) == cleaning {
-	}
+	r.mtx.Unlock()
+	r.waitTidy()
 	return nil
 }
 
 // close the rotating file if writer implements the io.Closer interface.
-// Updates writer, used, and timer.
+// Updates writer, used, and cancels the scheduled rotation.
 func (r *RotatingFile) close() error {
 	if closer, ok := r.writer.(io.Closer); ok {
 		if err := closer.Close(); err != nil {
@@ -304,9 +366,8 @@ func (r *RotatingFile) close() error {
 	}
 	r.writer = nil
 	r.used = 0
-	if r.timer != nil {
-		r.timer.Stop()
-	}
+	r.rotationPending = false
+	rotationScheduler.cancel(r)
 	return nil
 }
 
@@ -335,6 +396,7 @@ func (r *RotatingFile) openWriter() error {
 		}
 	}
 	r.writer = writer
+	r.schedule()
 	return nil
 }
 
@@ -356,14 +418,32 @@ func (r *RotatingFile) createFile(file string, flag int, perm os.FileMode) (fd *
 
 // rotate closes the current file descriptor and creates a new rotated file.
 // It also attempts to clean up and compress the backups files asynchronously.
-func (r *RotatingFile) rotate() error {
-	err := r.close()
+// A failure is notified to the Observer by the cleanup goroutine.
+func (r *RotatingFile) rotate() (err error) {
+	// the cleanup goroutine must not compress the backup files being shifted, the rotation
+	// is done by the goroutine when it finishes rather than waiting for it, whether it is
+	// due to the size or to the time
+	if r.shifts() && atomic.LoadUint32(&r.cleaning) == cleaning {
+		r.rotationPending = true
+		return nil
+	}
+	defer func() {
+		if err != nil && r.option.Observer != nil {
+			r.rotations.pushError(err)
+			r.tidyBackups()
+		}
+	}()
+	err = r.close()
 	if err != nil {
 		return errors.Newf("failed to close file: %s, err: %s", r.file, err)
 	}
 	// when both Backups and MaxAge are not equal to 0, a new file is created.
 	if r.option.Backups != 0 && r.option.MaxAge != 0 {
-		backupFile := filepath.Join(r.folder, r.nextBackupFilename())
+		var backupFile string
+		backupFile, err = r.nextBackupFile(time.Now())
+		if err != nil {
+			return err
+		}
 		err = osRename(r.file, backupFile)
 		if err != nil {
 			if errors.Is(err, os.ErrNotExist) {
@@ -371,6 +451,8 @@ func (r *RotatingFile) rotate() error {
 			} else {
 				return errors.Newf("failed to backup file: %q, err: %s", backupFile, err)
 			}
+		} else if r.option.Observer != nil {
+			r.rotations.push(r.file, backupFile)
 		}
 		// cleanup expired backups and compress backup files
 		r.tidyBackups()
@@ -381,10 +463,11 @@ func (r *RotatingFile) rotate() error {
 		return errors.Newf("failed to open rotating file: %s", err)
 	}
 	r.writer = fd
-	// update rotatingTime and reset timer if used time-based rotation is enabled
-	if r.option.Duration > 0 {
+	// update rotatingTime and reschedule if used time-based rotation is enabled
+	if r.option.Schedule != nil || r.option.Duration > 0 {
 		r.rotatingTime = time.Now()
-		r.timer.Reset(r.option.Duration)
+		r.nextRotatingTime = r.nextRotation(r.rotatingTime)
+		r.schedule()
 	}
 	if r.option.MaxSize > 0 {
 		r.used = 0
@@ -392,6 +475,36 @@ func (r *RotatingFile) rotate() error {
 	return nil
 }
 
+// nextRotation returns the time of the next time-based rotation after now, the zero time
+// if the time-based rotation is disabled.
+func (r *RotatingFile) nextRotation(now time.Time) time.Time {
+	if r.option.Schedule != nil {
+		return r.option.Schedule.Next(now.In(r.option.Location))
+	}
+	if r.option.Duration > 0 {
+		return now.Add(r.option.Duration)
+	}
+	return time.Time{}
+}
+
+// schedule schedules the time-based rotation at nextRotatingTime, a rotation time that
+// passed while the file was closed rotates it at once.
+func (r *RotatingFile) schedule() {
+	if !r.nextRotatingTime.IsZero() {
+		rotationScheduler.schedule(r, r.nextRotatingTime)
+	}
+}
+
+// scheduledRotate is called by the rotationScheduler at the rotation time at, the rotation
+// is skipped if the file was closed or rotated by size for another rotation time since.
+func (r *RotatingFile) scheduledRotate(at time.Time) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.writer != nil && r.nextRotatingTime.Equal(at) {
+		errors.Warning(r.rotate())
+	}
+}
+
 // nextBackupFilename returns the name of the next backup file.
 func (r *RotatingFile) nextBackupFilename() string {
 	sb := &strings.Builder{}
@@ -404,17 +517,84 @@ func (r *RotatingFile) nextBackupFilename() string {
 	return sb.String()
 }
 
-// tidyBackups deletes the expired backups and compresses backup files
+// nextBackupFile returns the abs path of the next backup file rotated at now.
+// With an {index} template, the existing backups are shifted first.
+func (r *RotatingFile) nextBackupFile(now time.Time) (string, error) {
+	switch {
+	case r.template == nil:
+		return filepath.Join(r.folder, r.nextBackupFilename()), nil
+	case r.template.shift:
+		return filepath.Join(r.folder, r.template.render(now, 1)), r.shiftBackups()
+	}
+	for seq := 0; ; seq++ {
+		file := filepath.Join(r.folder, r.template.render(now, seq))
+		if !exists(file) && !exists(file+compressExtension) {
+			return file, nil
+		}
+	}
+}
+
+// exists reports whether the file exists.
+func exists(file string) bool {
+	_, err := osStat(file)
+	return err == nil
+}
+
+// shifts reports whether the rotation shifts the backup files, see LogrotateTemplate.
+func (r *RotatingFile) shifts() bool {
+	return r.template != nil && r.template.shift && r.option.Backups != 0 && r.option.MaxAge != 0
+}
+
+// shiftBackups increments the index of the backup files, the oldest first, so that the
+// index 1 is free for the next backup file. The cleanup goroutine must not be running.
+func (r *RotatingFile) shiftBackups() error {
+	backups, err := r.sortBackups()
+	if err != nil {
+		return err
+	}
+	for _, bk := range backups {
+		dst := filepath.Join(r.folder, r.template.render(bk.modTime, bk.seq+1))
+		if strings.HasSuffix(bk.file, compressExtension) {
+			dst += compressExtension
+		}
+		if err = osRename(bk.file, dst); err != nil {
+			return errors.Newf("failed to shift backup file: %q, err: %s", bk.file, err)
+		}
+	}
+	return nil
+}
+
+// waitTidy waits for the cleanup goroutine to finish, including the goroutines restarted
+// by finishTidy. r.mtx must not be held.
+func (r *RotatingFile) waitTidy() {
+	tidied, _ := r.tidied.Load().(chan struct{})
+	for tidied != nil {
+		<-tidied
+		next, _ := r.tidied.Load().(chan struct{})
+		if next == tidied {
+			return
+		}
+		tidied = next
+	}
+}
+
+// tidyBackups deletes the expired backups and compresses backup files, r.mtx must be held.
 func (r *RotatingFile) tidyBackups() {
 	// existed a running cleanup goroutine
 	if !atomic.CompareAndSwapUint32(&r.cleaning, noCleaning, cleaning) {
 		return
 	}
+	tidied := make(chan struct{})
+	r.tidied.Store(tidied)
 	// start a cleanup goroutine to delete the expired backups
 	go func() {
-		defer atomic.StoreUint32(&r.cleaning, noCleaning)
+		defer r.finishTidy(tidied)
+		if r.option.Observer != nil {
+			r.notifyRotations()
+		}
 		bks, err := r.cleanBackups()
 		errors.Warning(err)
+		r.notifyError("clean", err)
 		// compress backup files if compressLevel > 0
 		if r.option.CompressLevel <= 0 {
 			return
@@ -422,15 +602,29 @@ func (r *RotatingFile) tidyBackups() {
 		for _, bk := range bks {
 			// avoid compressed file
 			if !strings.HasSuffix(bk.file, compressExtension) {
-				errors.Warning(compressFile(
-					bk.file,
-					bk.file+compressExtension,
-					r.option.CompressLevel))
+				r.compressBackupFile(bk.file)
 			}
 		}
 	}()
 }
 
+// finishTidy marks the cleanup goroutine as finished, does the rotation postponed meanwhile,
+// see rotate, and starts another goroutine to notify the rotations queued while exiting.
+// tidied is closed last, so that waitTidy also waits for the restarted goroutine.
+func (r *RotatingFile) finishTidy(tidied chan struct{}) {
+	defer close(tidied)
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	atomic.StoreUint32(&r.cleaning, noCleaning)
+	// a closed file is not rotated, it is checked again when it is opened
+	if r.rotationPending && r.writer != nil {
+		errors.Warning(r.rotate())
+	}
+	if r.rotations.pending() {
+		r.tidyBackups()
+	}
+}
+
 // cleanBackups performs garbage collection (cleanup) of old backup files.
 // It deletes the oldest backup files until the maximum number of backup files is reached.
 func (r *RotatingFile) cleanBackups() ([]backupFile, error) {
@@ -445,30 +639,84 @@ func (r *RotatingFile) cleanBackups() ([]backupFile, error) {
 		return nil, nil
 	}
 
-	deleteIndex := 0
+	backupsIndex := 0
 	// calculate the index of the oldest backup file to delete based on Backups
 	if r.option.Backups > 0 {
 		if left := length - r.option.Backups; left > 0 {
-			deleteIndex = left
+			backupsIndex = left
 		}
 	}
 
 	// calculate the index of the oldest backup file to delete based on MaxAge
+	ageIndex := backupsIndex
 	if r.option.MaxAge > 0 {
 		expired := time.Now().Add(-r.option.MaxAge)
 		index := findExpiredIndex(backups, expired)
 		if index == -1 {
-			deleteIndex = length
+			ageIndex = length
 		} else {
-			deleteIndex = lib.Max(index, deleteIndex)
+			ageIndex = lib.Max(index, backupsIndex)
 		}
 	}
-	if deleteIndex > 0 {
-		deleteBackupFiles(backups[:deleteIndex])
+
+	// calculate the index of the oldest backup file to delete based on MaxTotalSize and
+	// MinFreeSpace
+	sizeIndex, spaceIndex := r.budgetIndex(backups, ageIndex)
+
+	// every deleted backup file is attributed to the first strategy that deletes it
+	deleteIndex := 0
+	for _, strategy := range []struct {
+		index  int
+		reason string
+	}{
+		{backupsIndex, BackupsReason},
+		{ageIndex, MaxAgeReason},
+		{sizeIndex, MaxTotalSizeReason},
+		{spaceIndex, MinFreeSpaceReason},
+	} {
+		if strategy.index > deleteIndex {
+			r.deleteBackupFiles(backups[deleteIndex:strategy.index], strategy.reason)
+			deleteIndex = strategy.index
+		}
 	}
 	return backups[deleteIndex:], nil
 }
 
+// budgetIndex returns the indexes of the oldest backup files to keep, from start, so that
+// the backup files and the current file fit in MaxTotalSize, and then so that the disk
+// keeps MinFreeSpace available.
+func (r *RotatingFile) budgetIndex(backups []backupFile, start int) (sizeIndex, spaceIndex int) {
+	index := start
+	if r.option.MaxTotalSize > 0 {
+		var total int64
+		if info, err := osStat(r.file); err == nil {
+			total = info.Size()
+		}
+		for i := start; i < len(backups); i++ {
+			total += backups[i].size
+		}
+		for ; index < len(backups) && total > r.option.MaxTotalSize; index++ {
+			total -= backups[index].size
+		}
+	}
+	sizeIndex = index
+	if r.option.MinFreeSpace > 0 {
+		free, err := diskFreeSpace(r.folder)
+		if err != nil {
+			errors.Warningf("failed to get free space of %q, err: %s", r.folder, err)
+			return sizeIndex, sizeIndex
+		}
+		// the backup files deleted by the previous strategies free their space
+		for i := 0; i < index; i++ {
+			free += backups[i].size
+		}
+		for ; index < len(backups) && free < r.option.MinFreeSpace; index++ {
+			free += backups[index].size
+		}
+	}
+	return sizeIndex, index
+}
+
 // findExpiredIndex returns the index of the first backup file that is expired.
 func findExpiredIndex(backups []backupFile, expired time.Time) int {
 	for index := range backups {
@@ -479,12 +727,17 @@ func findExpiredIndex(backups []backupFile, expired time.Time) int {
 	return -1
 }
 
-// sortBackups returns a list of backup files sorted by modification time.
+// sortBackups returns a list of backup files sorted by modification time, the oldest
+// first. With a BackupTemplate, they are sorted by the rotation time and the sequence, or
+// by the index, parsed from their names.
 func (r *RotatingFile) sortBackups() ([]backupFile, error) {
 	files, err := osReadDir(r.folder)
 	if err != nil {
 		return nil, errors.Newf("failed to list backup files, err: %s", err)
 	}
+	if r.template != nil {
+		return r.sortTemplateBackups(files)
+	}
 	backups := make([]backupFile, 0, len(files))
 	var info os.FileInfo
 	for index := range files {
@@ -503,6 +756,7 @@ func (r *RotatingFile) sortBackups() ([]backupFile, error) {
 		bk := backupFile{
 			file:    filepath.Join(r.folder, name),
 			modTime: info.ModTime(),
+			size:    info.Size(),
 		}
 		backups = append(backups, bk)
 	}
@@ -513,6 +767,49 @@ func (r *RotatingFile) sortBackups() ([]backupFile, error) {
 	return backups, nil
 }
 
+// sortTemplateBackups returns the backup files named by the template, sorted by the
+// rotation time and the sequence, or by the index in descending order.
+func (r *RotatingFile) sortTemplateBackups(files []os.DirEntry) ([]backupFile, error) {
+	backups := make([]backupFile, 0, len(files))
+	for index := range files {
+		name := files[index].Name()
+		if files[index].IsDir() {
+			continue
+		}
+		at, seq, ok := r.template.parse(name)
+		if !ok {
+			continue
+		}
+		bk := backupFile{
+			file:    filepath.Join(r.folder, name),
+			modTime: at,
+			seq:     seq,
+		}
+		// the {index} names have no time, MaxAge uses the modification time
+		if r.template.shift || r.option.MaxTotalSize > 0 || r.option.MinFreeSpace > 0 {
+			info, err := files[index].Info()
+			if err != nil {
+				return nil, errors.Newf("failed to get file: %q, err: %s", name, err)
+			}
+			if r.template.shift {
+				bk.modTime = info.ModTime()
+			}
+			bk.size = info.Size()
+		}
+		backups = append(backups, bk)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if r.template.shift {
+			return backups[i].seq > backups[j].seq
+		}
+		if !backups[i].modTime.Equal(backups[j].modTime) {
+			return backups[i].modTime.Before(backups[j].modTime)
+		}
+		return backups[i].seq < backups[j].seq
+	})
+	return backups, nil
+}
+
 // SetOption is configuring rotating file function types
 type SetOption func(*Option) error
 
@@ -593,6 +890,100 @@ func WithDuration(duration time.Duration) SetOption {
 	}
 }
 
+// WithMaxTotalSize limits the total size of the backup files and the current file.
+func WithMaxTotalSize(size int64) SetOption {
+	return func(opt *Option) error {
+		if size > 0 && size < 1<<12 {
+			errors.Warningf("too small max total size:%d, backups may be deleted at once", size)
+		}
+		opt.MaxTotalSize = size
+		return nil
+	}
+}
+
+// WithMaxTotalSizeString is WithMaxTotalSize with the size parsed by lib.String2Size,
+// e.g. "20 GB".
+func WithMaxTotalSizeString(size string) SetOption {
+	return func(opt *Option) error {
+		n, err := parseSize(size)
+		if err != nil {
+			return err
+		}
+		return WithMaxTotalSize(n)(opt)
+	}
+}
+
+// WithMinFreeSpace deletes the oldest backup files when the disk has less free space.
+func WithMinFreeSpace(size int64) SetOption {
+	return func(opt *Option) error {
+		opt.MinFreeSpace = size
+		return nil
+	}
+}
+
+// WithMinFreeSpaceString is WithMinFreeSpace with the size parsed by lib.String2Size,
+// e.g. "1 GB".
+func WithMinFreeSpaceString(size string) SetOption {
+	return func(opt *Option) error {
+		n, err := parseSize(size)
+		if err != nil {
+			return err
+		}
+		return WithMinFreeSpace(n)(opt)
+	}
+}
+
+// parseSize parses the size with lib.String2Size, the error wraps InvalidSizeError.
+func parseSize(size string) (int64, error) {
+	n, err := lib.String2Size(size)
+	if err != nil {
+		return 0, errors.Newf("%s %q, err: %s", InvalidSizeError, size, err)
+	}
+	return n, nil
+}
+
+// WithBackupTemplate names the backup files with the template, e.g. TimestampTemplate or
+// LogrotateTemplate, see Option.BackupTemplate.
+func WithBackupTemplate(tmpl string) SetOption {
+	return func(opt *Option) error {
+		if tmpl != "" {
+			if _, err := parseTemplate(tmpl); err != nil {
+				return err
+			}
+		}
+		opt.BackupTemplate = tmpl
+		return nil
+	}
+}
+
+// WithSchedule aligns the time-based rotation to the wall clock, e.g. Hourly, Daily,
+// Weekly or a cron expression parsed by ParseSchedule, nil uses Duration.
+func WithSchedule(schedule Schedule) SetOption {
+	return func(opt *Option) error {
+		opt.Schedule = schedule
+		return nil
+	}
+}
+
+// WithLocation sets the time zone of the schedule.
+func WithLocation(loc *time.Location) SetOption {
+	return func(opt *Option) error {
+		if loc == nil {
+			return InvalidLocationError
+		}
+		opt.Location = loc
+		return nil
+	}
+}
+
+// WithObserver sets the observer of the backup files, nil disables it.
+func WithObserver(observer Observer) SetOption {
+	return func(opt *Option) error {
+		opt.Observer = observer
+		return nil
+	}
+}
+
 // NewRotatingFile creates a new rotating file with the specified options.
 func NewRotatingFile(file string, opts ...SetOption) (*RotatingFile, error) {
 
@@ -618,25 +1009,14 @@ func NewRotatingFile(file string, opts ...SetOption) (*RotatingFile, error) {
 	if err != nil {
 		return nil, errors.Newf("failed to set option, err: %s", err)
 	}
-
-	// active daemon goroutine
-	if r.option.Duration > 0 {
-		r.timer = time.NewTimer(r.option.Duration)
-		go func() {
-			for {
-				select {
-				case now := <-r.timer.C:
-					func() {
-						r.mtx.Lock()
-						defer r.mtx.Unlock()
-						if r.writer != nil && now.Sub(r.rotatingTime) > r.option.Duration {
-							errors.Warning(r.rotate())
-						}
-					}()
-				default:
-				}
-			}
-		}()
+	if r.option.BackupTemplate != "" {
+		r.template, err = newBackupTemplate(r.option.BackupTemplate, r.option.BackupPrefix, filename, r.option.Location)
+		if err != nil {
+			return nil, err
+		}
 	}
+
+	// the time-based rotation is scheduled when the writer is opened
+	r.nextRotatingTime = r.nextRotation(time.Now())
 	return r, nil
 }
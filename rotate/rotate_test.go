@@ -8,7 +8,9 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -31,6 +33,7 @@ import (
 func TestDeleteBackupFiles(t *testing.T) {
 
 	folder := t.TempDir()
+	f := &RotatingFile{option: defaultOption.clone()}
 	defer os.RemoveAll(folder)
 
 	t.Run("delete existed file", func(t *testing.T) {
@@ -41,7 +44,7 @@ func TestDeleteBackupFiles(t *testing.T) {
 		require.True(t, paths.IsExisted(absFile))
 		require.NoError(t, err)
 		buf := &bytes.Buffer{}
-		deleteBackupFiles([]backupFile{{file: absFile}})
+		f.deleteBackupFiles([]backupFile{{file: absFile}}, BackupsReason)
 		errors.SetWarningOutput(buf)
 		warningText := buf.String()
 		require.True(t, len(warningText) == 0)
@@ -50,7 +53,7 @@ func TestDeleteBackupFiles(t *testing.T) {
 	t.Run("delete not existed file", func(t *testing.T) {
 		buf := &bytes.Buffer{}
 		errors.SetWarningOutput(buf)
-		deleteBackupFiles([]backupFile{{file: lib.RandString(8)}, {file: lib.RandString(8)}})
+		f.deleteBackupFiles([]backupFile{{file: lib.RandString(8)}, {file: lib.RandString(8)}}, BackupsReason)
 		require.Contains(t, buf.String(), "failed to remove")
 	})
 }
@@ -86,12 +89,9 @@ func TestCompressFile(t *testing.T) {
 	t.Run("failed to compress file", func(t *testing.T) {
 
 		// not exist src file
-		buf := &bytes.Buffer{}
-		errors.SetWarningOutput(buf)
-		defer errors.SetWarningOutput(os.Stderr)
 		err := compressFile("not-existed-file", "not-existed-file.gz", 6)
-		require.NoError(t, err)
-		require.Contains(t, buf.String(), "no such file or directory")
+		require.ErrorIs(t, err, os.ErrNotExist)
+		require.NoFileExists(t, "not-existed-file.gz")
 
 		// cannot get file stat
 		osOpen = func(name string) (*os.File, error) {
@@ -252,11 +252,13 @@ func TestClose(t *testing.T) {
 		testFile := filepath.Join(testDir, lib.RandString(6))
 		f, err := NewRotatingFile(testFile, WithMaxSize(10), WithDuration(-1))
 		require.NoError(t, err)
-		require.Nil(t, f.timer)
+		require.True(t, f.nextRotatingTime.IsZero())
 		require.Equal(t, int64(0), f.used)
+		_, err = f.WriteString("hello")
+		require.NoError(t, err)
+		require.False(t, isScheduled(f))
 		err = f.Close()
 		require.NoError(t, err)
-		require.Nil(t, f.timer)
 		require.Nil(t, f.writer)
 	})
 	t.Run("duration", func(t *testing.T) {
@@ -265,11 +267,15 @@ func TestClose(t *testing.T) {
 		testFile := filepath.Join(testDir, lib.RandString(6))
 		f, err := NewRotatingFile(testFile, WithMaxSize(-1), WithDuration(lib.Day))
 		require.NoError(t, err)
-		require.NotNil(t, f.timer)
+		require.False(t, f.nextRotatingTime.IsZero())
 		require.Equal(t, int64(0), f.used)
+		_, err = f.WriteString("hello")
+		require.NoError(t, err)
+		require.True(t, isScheduled(f))
 		err = f.Close()
 		require.NoError(t, err)
 		require.Nil(t, f.writer)
+		require.False(t, isScheduled(f))
 	})
 	t.Run("failed", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -349,6 +355,108 @@ func TestRotatingFileCleanBackups(t *testing.T) {
 		require.Equal(t, 0, len(bks))
 	})
 
+	// createBackups creates backup files of 100 bytes, the oldest first, and the current
+	// file of 100 bytes.
+	createBackups := func(t *testing.T, count int) []string {
+		err := paths.Clear(f.folder)
+		require.NoError(t, err)
+		f.option.MaxAge = lib.Month
+		files := make([]string, 0, count)
+		for i := 0; i < count; i++ {
+			file := filepath.Join(f.folder, f.nextBackupFilename())
+			require.NoError(t, os.WriteFile(file, make([]byte, 100), 0o644))
+			modTime := time.Now().Add(time.Duration(i-count) * time.Minute)
+			require.NoError(t, os.Chtimes(file, modTime, modTime))
+			files = append(files, file)
+		}
+		require.NoError(t, os.WriteFile(f.file, make([]byte, 100), 0o644))
+		return files
+	}
+
+	t.Run("clean by max total size", func(t *testing.T) {
+		files := createBackups(t, 5)
+		f.option.MaxTotalSize = 350
+		defer func() {
+			f.option.MaxTotalSize = 0
+		}()
+		bks, err := f.cleanBackups()
+		require.NoError(t, err)
+		// the current file and 2 backups
+		require.Equal(t, 2, len(bks))
+		require.Equal(t, files[3], bks[0].file)
+		require.NoFileExists(t, files[2])
+		require.FileExists(t, files[3])
+	})
+
+	t.Run("clean by min free space", func(t *testing.T) {
+		files := createBackups(t, 5)
+		f.option.MinFreeSpace = 1000
+		diskFreeSpace = func(folder string) (int64, error) {
+			require.Equal(t, f.folder, folder)
+			return 750, nil
+		}
+		defer func() {
+			f.option.MinFreeSpace = 0
+			diskFreeSpace = freeSpace
+		}()
+		bks, err := f.cleanBackups()
+		require.NoError(t, err)
+		require.Equal(t, 2, len(bks))
+		require.Equal(t, files[3], bks[0].file)
+
+		// the backups deleted by the total size free their space
+		files = createBackups(t, 5)
+		f.option.MaxTotalSize = 450
+		f.option.MinFreeSpace = 950
+		defer func() {
+			f.option.MaxTotalSize = 0
+		}()
+		bks, err = f.cleanBackups()
+		require.NoError(t, err)
+		require.Equal(t, 3, len(bks))
+		require.Equal(t, files[2], bks[0].file)
+
+		// the backups deleted by Backups free their space
+		files = createBackups(t, 5)
+		f.option.MaxTotalSize = 0
+		f.option.MinFreeSpace = 1000
+		f.option.Backups = 3
+		bks, err = f.cleanBackups()
+		f.option.Backups = defaultOption.Backups
+		require.NoError(t, err)
+		require.Equal(t, 2, len(bks))
+		require.Equal(t, []string{files[3], files[4]}, []string{bks[0].file, bks[1].file})
+
+		// the backups deleted by MaxAge free their space
+		files = createBackups(t, 5)
+		f.option.MaxAge = 150 * time.Second
+		bks, err = f.cleanBackups()
+		require.NoError(t, err)
+		require.Equal(t, 2, len(bks))
+		require.Equal(t, []string{files[3], files[4]}, []string{bks[0].file, bks[1].file})
+
+		// the free space is unknown
+		buf := &bytes.Buffer{}
+		errors.SetWarningOutput(buf)
+		defer errors.SetWarningOutput(os.Stderr)
+		diskFreeSpace = func(string) (int64, error) {
+			return 0, os.ErrPermission
+		}
+		createBackups(t, 5)
+		f.option.MaxTotalSize = 0
+		bks, err = f.cleanBackups()
+		require.NoError(t, err)
+		require.Equal(t, 5, len(bks))
+		require.Contains(t, buf.String(), "failed to get free space")
+	})
+}
+
+func TestFreeSpace(t *testing.T) {
+	free, err := freeSpace(t.TempDir())
+	require.NoError(t, err)
+	require.Greater(t, free, int64(0))
+	_, err = freeSpace(filepath.Join(t.TempDir(), "not-existed-dir"))
+	require.Error(t, err)
 }
 
 func TestRotatingFileRotate(t *testing.T) {
@@ -359,6 +467,13 @@ func TestRotatingFileRotate(t *testing.T) {
 	f, err := NewRotatingFile(testFile)
 	require.NoError(t, err)
 	defer f.Close()
+	// rotate like Write, the cleanup goroutine started by the rotation uses the replaced
+	// functions, so it is waited for before they are restored
+	rotate := func() error {
+		f.mtx.Lock()
+		defer f.mtx.Unlock()
+		return f.rotate()
+	}
 
 	//not found src file
 	osRename = func(oldpath, newpath string) error {
@@ -367,8 +482,9 @@ func TestRotatingFileRotate(t *testing.T) {
 	buf := &bytes.Buffer{}
 	errors.SetWarningOutput(buf)
 	//defer errors.SetWarningOutput(os.Stderr)
-	err = f.rotate()
+	err = rotate()
 	require.NoError(t, err)
+	f.waitTidy()
 	require.Contains(t, buf.String(), "failed to backup file")
 	osRename = os.Rename
 	errors.SetWarningOutput(os.Stderr)
@@ -377,16 +493,18 @@ func TestRotatingFileRotate(t *testing.T) {
 	osRename = func(oldpath, newpath string) error {
 		return os.ErrInvalid
 	}
-	err = f.rotate()
+	err = rotate()
 	require.ErrorIs(t, err, os.ErrInvalid)
+	f.waitTidy()
 	osRename = os.Rename
 
 	// failed to create new file
 	osOpenFile = func(name string, flag int, perm os.FileMode) (*os.File, error) {
 		return nil, os.ErrPermission
 	}
-	err = f.rotate()
+	err = rotate()
 	require.ErrorIs(t, err, os.ErrPermission)
+	f.waitTidy()
 	osOpenFile = os.OpenFile
 
 }
@@ -508,6 +626,34 @@ func TestNewRotatingFile(t *testing.T) {
 		require.Equal(t, -1, f.option.Backups)
 		require.Contains(t, buf.String(), "not limited by backups")
 	})
+
+	t.Run("size strings", func(t *testing.T) {
+		f, err := NewRotatingFile(filepath.Join(testDir, lib.RandString(6)),
+			WithMaxTotalSizeString("20 GB"), WithMinFreeSpaceString("1k"))
+		require.NoError(t, err)
+		require.Equal(t, 20*lib.GB, f.option.MaxTotalSize)
+		require.Equal(t, lib.KB, f.option.MinFreeSpace)
+
+		f, err = NewRotatingFile(filepath.Join(testDir, lib.RandString(6)), WithMaxTotalSizeString("huge"))
+		require.ErrorIs(t, err, InvalidSizeError)
+		require.Nil(t, f)
+		f, err = NewRotatingFile(filepath.Join(testDir, lib.RandString(6)), WithMinFreeSpaceString("-1 GB"))
+		require.ErrorIs(t, err, InvalidSizeError)
+		require.Nil(t, f)
+	})
+
+	t.Run("schedule", func(t *testing.T) {
+		f, err := NewRotatingFile(filepath.Join(testDir, lib.RandString(6)), WithSchedule(Daily), WithLocation(time.UTC))
+		require.NoError(t, err)
+		defer f.Close()
+		require.False(t, isScheduled(f))
+		require.Equal(t, time.UTC, f.nextRotatingTime.Location())
+		require.Equal(t, time.Now().UTC().Truncate(lib.Day).Add(lib.Day), f.nextRotatingTime)
+
+		f, err = NewRotatingFile(filepath.Join(testDir, lib.RandString(6)), WithSchedule(Daily), WithLocation(nil))
+		require.ErrorIs(t, err, InvalidLocationError)
+		require.Nil(t, f)
+	})
 }
 
 // -·-·-·-·-·-·--·-·-·-·-
@@ -725,6 +871,14 @@ func TestLogicCompress(t *testing.T) {
 	// TODO: add compress test
 }
 
+// state returns the used size and the time of the last rotation of the file, under its
+// mutex since the scheduler and the cleanup goroutine rotate it concurrently.
+func state(f *RotatingFile) (used int64, rotatingTime time.Time) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.used, f.rotatingTime
+}
+
 func TestLogicRotate(t *testing.T) {
 
 	// test size rotate
@@ -736,7 +890,7 @@ func TestLogicRotate(t *testing.T) {
 		f, err := NewRotatingFile(testFile, WithMaxSize(10), WithDuration(0))
 		require.NoError(t, err)
 		// ensure config is correct
-		require.Nil(t, f.timer)
+		require.True(t, f.nextRotatingTime.IsZero())
 		require.True(t, f.rotatingTime.IsZero())
 		require.Equal(t, int64(10), f.option.MaxSize)
 		require.Equal(t, int64(0), f.used)
@@ -762,7 +916,7 @@ func TestLogicRotate(t *testing.T) {
 		require.NoError(t, err)
 
 		// ensure config is correct
-		require.NotNil(t, f.timer)
+		require.False(t, f.nextRotatingTime.IsZero())
 		require.True(t, f.rotatingTime.IsZero())
 		require.Equal(t, int64(0), f.used)
 		require.Equal(t, int64(0), f.option.MaxSize)
@@ -776,11 +930,14 @@ func TestLogicRotate(t *testing.T) {
 		require.Equal(t, 0, len(files))
 
 		// ensure backup file is created
-		f.timer.Reset(duration)
+		f.mtx.Lock()
+		f.nextRotatingTime = time.Now().Add(duration)
+		f.mtx.Unlock()
 		n, err := f.WriteString(lib.RandString(15))
 		require.NoError(t, err)
 		require.Equal(t, 15, n)
-		require.Equal(t, int64(0), f.used)
+		used, _ := state(f)
+		require.Equal(t, int64(0), used)
 		time.Sleep(time.Duration(float64(duration) * 1.5))
 		err = f.Close()
 		files, err = f.sortBackups()
@@ -801,7 +958,7 @@ func TestLogicRotate(t *testing.T) {
 		)
 		require.NoError(t, err)
 		// ensure config is correct
-		require.NotNil(t, f.timer)
+		require.False(t, f.nextRotatingTime.IsZero())
 		require.True(t, f.rotatingTime.IsZero())
 		require.Equal(t, int64(0), f.used)
 		require.Equal(t, int64(20), f.option.MaxSize)
@@ -816,19 +973,22 @@ func TestLogicRotate(t *testing.T) {
 		require.Equal(t, 0, len(files))
 
 		// ensure backup file is created by duration rotate
-		f.timer.Reset(duration)
+		f.mtx.Lock()
+		f.nextRotatingTime = time.Now().Add(duration)
+		f.mtx.Unlock()
 		require.True(t, f.rotatingTime.IsZero())
 		n, err := f.WriteString(lib.RandString(15))
 		require.NoError(t, err)
 		require.Equal(t, 15, n)
-		require.Equal(t, int64(15), f.used)
+		used, _ := state(f)
+		require.Equal(t, int64(15), used)
 		time.Sleep(time.Duration(float64(duration) * 1.5))
 		err = f.Close()
-		require.False(t, f.rotatingTime.IsZero())
+		_, durationRotateTime := state(f)
+		require.False(t, durationRotateTime.IsZero())
 		files, err = f.sortBackups()
 		require.NoError(t, err)
 		require.Equal(t, 1, len(files))
-		durationRotateTime := f.rotatingTime
 
 		// ensure backup file is created by size rotate
 		n, err = f.WriteString(lib.RandString(25))
@@ -836,14 +996,225 @@ func TestLogicRotate(t *testing.T) {
 		require.Equal(t, 25, n)
 
 		// ensure not reached max size rotate
-		require.Equal(t, int64(0), f.used)
-		require.True(t, f.rotatingTime.After(durationRotateTime))
+		used, rotatingTime := state(f)
+		require.Equal(t, int64(0), used)
+		require.True(t, rotatingTime.After(durationRotateTime))
 		err = f.Close()
 		require.NoError(t, err)
 
 	})
 }
 
+// intervalSchedule rotates at the multiples of the interval since the zero time.
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Truncate(time.Duration(s)).Add(time.Duration(s))
+}
+
+func TestLogicScheduleRotate(t *testing.T) {
+
+	t.Run("aligned rotate", func(t *testing.T) {
+		testDir := t.TempDir()
+		testFile := filepath.Join(testDir, "schedule_rotate.txt")
+		interval := 500 * time.Millisecond
+		f, err := NewRotatingFile(testFile, WithMaxSize(0), WithSchedule(intervalSchedule(interval)))
+		require.NoError(t, err)
+		defer f.Close()
+		next := f.nextRotatingTime
+		require.Equal(t, next.Truncate(interval), next)
+		times := func() (rotating, next time.Time) {
+			f.mtx.Lock()
+			defer f.mtx.Unlock()
+			return f.rotatingTime, f.nextRotatingTime
+		}
+
+		// writer is nil, so the file is not scheduled.
+		require.False(t, isScheduled(f))
+		_, err = f.WriteString(lib.RandString(15))
+		require.NoError(t, err)
+		require.True(t, isScheduled(f))
+		require.Eventually(t, func() bool {
+			rotating, later := times()
+			return !rotating.Before(next) && later.After(next) && later.Equal(later.Truncate(interval))
+		}, 5*time.Second, 10*time.Millisecond)
+		require.NoError(t, f.Close())
+		require.False(t, isScheduled(f))
+		files, err := f.sortBackups()
+		require.NoError(t, err)
+		require.Equal(t, 1, len(files))
+
+		// the rotation time passed while the file was closed
+		_, next = times()
+		time.Sleep(time.Until(next) + interval/5)
+		rotating, _ := times()
+		require.True(t, rotating.Before(next))
+		_, err = f.WriteString(lib.RandString(15))
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			rotating, _ := times()
+			return !rotating.Before(next)
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("size rotate keeps schedule", func(t *testing.T) {
+		testDir := t.TempDir()
+		testFile := filepath.Join(testDir, "schedule_size_rotate.txt")
+		f, err := NewRotatingFile(testFile, WithMaxSize(20), WithSchedule(Hourly), WithLocation(time.UTC))
+		require.NoError(t, err)
+		defer f.Close()
+		next := f.nextRotatingTime
+		require.Equal(t, time.Now().UTC().Truncate(time.Hour).Add(time.Hour), next)
+
+		_, err = f.WriteString(lib.RandString(25))
+		require.NoError(t, err)
+		require.False(t, f.rotatingTime.IsZero())
+		require.Equal(t, next, f.nextRotatingTime)
+	})
+}
+
+func TestLogicBackupTemplate(t *testing.T) {
+
+	t.Run("timestamp", func(t *testing.T) {
+		testDir := t.TempDir()
+		testFile := filepath.Join(testDir, "timestamp.log")
+		f, err := NewRotatingFile(testFile, WithMaxSize(10), WithDuration(0), WithCompressLevel(0),
+			WithBackupTemplate(TimestampTemplate), WithLocation(time.UTC), WithMaxAge(lib.Month))
+		require.NoError(t, err)
+		defer f.Close()
+
+		// an expired backup by its name, though it was just modified
+		expired := filepath.Join(testDir, f.template.render(time.Now().Add(-2*lib.Month), 0))
+		require.NoError(t, os.WriteFile(expired, []byte("expired"), 0o644))
+
+		start := time.Now().Truncate(time.Second)
+		for index := 0; index < 3; index++ {
+			_, err = f.WriteString(strings.Repeat(strconv.Itoa(index), 15))
+			require.NoError(t, err)
+		}
+		require.NoError(t, f.Close())
+		require.NoFileExists(t, expired)
+
+		backups, err := f.sortBackups()
+		require.NoError(t, err)
+		require.Equal(t, 3, len(backups))
+		for index, bk := range backups {
+			require.False(t, bk.modTime.Before(start))
+			require.Equal(t, filepath.Join(testDir, f.template.render(bk.modTime, bk.seq)), bk.file)
+			data, err := os.ReadFile(bk.file)
+			require.NoError(t, err)
+			require.Equal(t, strings.Repeat(strconv.Itoa(index), 15), string(data))
+		}
+	})
+
+	t.Run("logrotate", func(t *testing.T) {
+		testDir := t.TempDir()
+		testFile := filepath.Join(testDir, "logrotate.log")
+		f, err := NewRotatingFile(testFile, WithMaxSize(10), WithDuration(0), WithCompressLevel(0),
+			WithBackupTemplate(LogrotateTemplate), WithBackups(3))
+		require.NoError(t, err)
+		defer f.Close()
+
+		for index := 0; index < 4; index++ {
+			_, err = f.WriteString(strings.Repeat(strconv.Itoa(index), 15))
+			require.NoError(t, err)
+			// the rotations are postponed while the cleanup goroutine runs
+			f.waitTidy()
+		}
+		require.NoError(t, f.Close())
+		for index := 1; index <= 3; index++ {
+			data, err := os.ReadFile(testFile + "." + strconv.Itoa(index))
+			require.NoError(t, err)
+			require.Equal(t, strings.Repeat(strconv.Itoa(4-index), 15), string(data))
+		}
+		require.NoFileExists(t, testFile+".4")
+	})
+
+	t.Run("logrotate compressed", func(t *testing.T) {
+		testDir := t.TempDir()
+		testFile := filepath.Join(testDir, "logrotate.log")
+		f, err := NewRotatingFile(testFile, WithMaxSize(10), WithDuration(0), WithBackupTemplate(LogrotateTemplate))
+		require.NoError(t, err)
+		defer f.Close()
+
+		for index := 0; index < 3; index++ {
+			_, err = f.WriteString(strings.Repeat(strconv.Itoa(index), 15))
+			require.NoError(t, err)
+			// the rotations are postponed while the cleanup goroutine runs
+			f.waitTidy()
+		}
+		require.NoError(t, f.Close())
+		for index := 1; index <= 3; index++ {
+			gzipFile, err := os.Open(testFile + "." + strconv.Itoa(index) + compressExtension)
+			require.NoError(t, err)
+			reader, err := gzip.NewReader(gzipFile)
+			require.NoError(t, err)
+			data, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			require.Equal(t, strings.Repeat(strconv.Itoa(3-index), 15), string(data))
+			require.NoError(t, gzipFile.Close())
+		}
+	})
+
+	t.Run("logrotate during cleanup", func(t *testing.T) {
+		testDir := t.TempDir()
+		testFile := filepath.Join(testDir, "logrotate.log")
+		f, err := NewRotatingFile(testFile, WithMaxSize(10), WithDuration(0), WithCompressLevel(0),
+			WithBackupTemplate(LogrotateTemplate))
+		require.NoError(t, err)
+		defer f.Close()
+		_, err = f.WriteString(strings.Repeat("0", 15))
+		require.NoError(t, err)
+		f.waitTidy()
+
+		// a running cleanup goroutine
+		startTidy := func() chan struct{} {
+			f.mtx.Lock()
+			defer f.mtx.Unlock()
+			require.True(t, atomic.CompareAndSwapUint32(&f.cleaning, noCleaning, cleaning))
+			tidied := make(chan struct{})
+			f.tidied.Store(tidied)
+			return tidied
+		}
+
+		// the scheduled rotation is postponed without blocking the scheduler
+		tidied := startTidy()
+		_, err = f.WriteString("1")
+		require.NoError(t, err)
+		f.mtx.Lock()
+		f.nextRotatingTime = time.Now()
+		f.mtx.Unlock()
+		f.scheduledRotate(f.nextRotatingTime)
+		require.NoFileExists(t, testFile+".2")
+		f.finishTidy(tidied)
+		f.waitTidy()
+		data, err := os.ReadFile(testFile + ".1")
+		require.NoError(t, err)
+		require.Equal(t, "1", string(data))
+		require.FileExists(t, testFile+".2")
+
+		// the size rotation is postponed without blocking the writes
+		tidied = startTidy()
+		_, err = f.WriteString(strings.Repeat("2", 15))
+		require.NoError(t, err)
+		_, err = f.WriteString("3")
+		require.NoError(t, err)
+		require.NoFileExists(t, testFile+".3")
+		f.finishTidy(tidied)
+		f.waitTidy()
+		data, err = os.ReadFile(testFile + ".1")
+		require.NoError(t, err)
+		require.Equal(t, strings.Repeat("2", 15)+"3", string(data))
+		require.FileExists(t, testFile+".3")
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		f, err := NewRotatingFile(filepath.Join(t.TempDir(), "invalid.log"), WithBackupTemplate("{name}{ext}"))
+		require.ErrorIs(t, err, InvalidBackupTemplateError)
+		require.Nil(t, f)
+	})
+}
+
 func TestLogicNewRotatingFile(t *testing.T) {
 	testDir := t.TempDir()
 	defer os.RemoveAll(testDir)
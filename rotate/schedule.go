@@ -0,0 +1,272 @@
+// Copyright 2021-2024 The utility Authors. All rights reserved.
+// Use of this source code is governed by the MIT license that can be found in the
+// LICENSE file
+
+package rotate
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stkali/utility/errors"
+)
+
+// Schedule aligns the time-based rotation to the wall clock, unlike Duration that rotates
+// relative to the previous rotation.
+type Schedule interface {
+	// Next returns the first rotation time after t, in the location of t.
+	Next(t time.Time) time.Time
+}
+
+var (
+	// Hourly rotates at the top of every hour.
+	Hourly = mustParseCron("0 * * * *")
+	// Daily rotates at midnight.
+	Daily = mustParseCron("0 0 * * *")
+	// Weekly rotates at midnight between Sunday and Monday.
+	Weekly = mustParseCron("0 0 * * 1")
+)
+
+// cronField is the set of the values matched by a field of a cron expression.
+type cronField uint64
+
+// has reports whether the field matches value.
+func (f cronField) has(value int) bool {
+	return f&(1<<uint(value)) != 0
+}
+
+// cronSchedule is a Schedule parsed from a cron expression.
+type cronSchedule struct {
+	minute, hour, dom, month, dow cronField
+	// anyDom and anyDow report whether the days of month and the days of week are `*`, the
+	// day must match both of them if one is `*` and either of them otherwise, like cron.
+	anyDom, anyDow bool
+}
+
+// maxCronYears limits the search of Next, the 29th of February matches at least once in 8
+// years. The expressions that never match are rejected by ParseSchedule.
+const maxCronYears = 8
+
+// Next implements the Schedule interface. A wall time skipped by a daylight saving time
+// transition matches at the first instant after it, like cron, and a repeated wall time
+// matches twice.
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	// the minutes and the hours are added instead of calling time.Date, which rewinds the
+	// wall clock in the repeated or skipped hour of a daylight saving time transition
+	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	limit := t.Year() + maxCronYears
+	for t.Year() <= limit {
+		var next time.Time
+		switch {
+		case !c.month.has(int(t.Month())):
+			next = forward(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+		case !c.matchDay(t):
+			next = forward(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+		case !c.hour.has(t.Hour()):
+			next = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case !c.minute.has(t.Minute()):
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+		if skipped, ok := c.matchSkipped(t, next); ok {
+			return skipped
+		}
+		t = next
+	}
+	return time.Time{}
+}
+
+// matchSkipped returns the end of the daylight saving time transition between from and to
+// if it skips a wall time matching the schedule.
+func (c *cronSchedule) matchSkipped(from, to time.Time) (time.Time, bool) {
+	gap := wall(to).Sub(wall(from)) - to.Sub(from)
+	if gap <= 0 {
+		return time.Time{}, false
+	}
+	// the transition is the first second whose wall time is ahead of the elapsed time
+	low, high := from.Unix(), to.Unix()
+	for high-low > 1 {
+		middle := low + (high-low)/2
+		if at := time.Unix(middle, 0).In(from.Location()); wall(at).Sub(wall(from)) > at.Sub(from) {
+			high = middle
+		} else {
+			low = middle
+		}
+	}
+	end := time.Unix(high, 0).In(from.Location())
+	for skipped := wall(end).Add(-gap); skipped.Before(wall(end)); skipped = skipped.Add(time.Minute) {
+		if c.month.has(int(skipped.Month())) && c.matchDay(skipped) && c.hour.has(skipped.Hour()) && c.minute.has(skipped.Minute()) {
+			return end, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// wall returns the wall time of t in UTC, where the wall times can be subtracted.
+func wall(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+// forward returns next if it is after t, or t plus an hour if the midnight of next is
+// skipped by a daylight saving time transition and time.Date rewound it.
+func forward(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Hour)
+}
+
+// matchDay reports whether the day of t matches the days of month and the days of week.
+func (c *cronSchedule) matchDay(t time.Time) bool {
+	dom, dow := c.dom.has(t.Day()), c.dow.has(int(t.Weekday()))
+	if c.anyDom || c.anyDow {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+var (
+	monthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	weekdayNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+)
+
+// ParseSchedule parses a schedule: "hourly", "daily" (or "midnight"), "weekly", optionally
+// prefixed with '@', or a cron expression of 5 fields: minute, hour, day of month, month
+// and day of week, e.g. "30 2 * * mon-fri" rotates at 02:30 on weekdays. A field is a
+// comma-separated list of `*`, values and ranges `a-b` optionally followed by a step `/n`,
+// the months and the days of week can be named by their first 3 letters, and both 0 and 7
+// are Sunday.
+func ParseSchedule(spec string) (Schedule, error) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(spec)), "@") {
+	case "hourly":
+		return Hourly, nil
+	case "daily", "midnight":
+		return Daily, nil
+	case "weekly":
+		return Weekly, nil
+	}
+	return parseCron(spec)
+}
+
+// parseCron parses a cron expression of 5 fields.
+func parseCron(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, errors.Newf("%s %q, err: expected 5 fields, got %d", InvalidScheduleError, spec, len(fields))
+	}
+	c := &cronSchedule{
+		anyDom: fields[2] == "*",
+		anyDow: fields[4] == "*",
+	}
+	var err error
+	for index, field := range []struct {
+		set      *cronField
+		min, max int
+		names    map[string]int
+	}{
+		{&c.minute, 0, 59, nil},
+		{&c.hour, 0, 23, nil},
+		{&c.dom, 1, 31, nil},
+		{&c.month, 1, 12, monthNames},
+		{&c.dow, 0, 7, weekdayNames},
+	} {
+		if *field.set, err = parseCronField(fields[index], field.min, field.max, field.names); err != nil {
+			return nil, errors.Newf("%s %q, err: %s", InvalidScheduleError, spec, err)
+		}
+	}
+	// 7 is Sunday
+	if c.dow.has(7) {
+		c.dow = c.dow&^(1<<7) | 1
+	}
+	if !c.matchable() {
+		return nil, errors.Newf("%s %q, err: the days never exist in the months", InvalidScheduleError, spec)
+	}
+	return c, nil
+}
+
+// daysInMonth are the most days of the months, the 29th of February included.
+var daysInMonth = [...]int{1: 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// matchable reports whether a day of the months matches, e.g. "0 0 30 2 *" never matches.
+// Any day matches if the days of week are not `*`.
+func (c *cronSchedule) matchable() bool {
+	if !c.anyDow {
+		return true
+	}
+	for month := 1; month <= 12; month++ {
+		for day := 1; day <= daysInMonth[month]; day++ {
+			if c.month.has(month) && c.dom.has(day) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mustParseCron parses the cron expression of a built-in Schedule.
+func mustParseCron(spec string) Schedule {
+	schedule, err := parseCron(spec)
+	if err != nil {
+		panic(err)
+	}
+	return schedule
+}
+
+// parseCronField parses a field of a cron expression whose values are in [min, max].
+func parseCronField(field string, min, max int, names map[string]int) (cronField, error) {
+	var set cronField
+	for _, item := range strings.Split(field, ",") {
+		expr, step := item, 1
+		if index := strings.IndexByte(item, '/'); index != -1 {
+			var err error
+			expr = item[:index]
+			if step, err = strconv.Atoi(item[index+1:]); err != nil || step <= 0 {
+				return 0, errors.Newf("invalid step in %q", item)
+			}
+		}
+		low, high := min, max
+		if expr != "*" {
+			bounds := strings.SplitN(expr, "-", 2)
+			var err error
+			if low, err = parseCronValue(bounds[0], min, max, names); err != nil {
+				return 0, err
+			}
+			switch {
+			case len(bounds) == 2:
+				if high, err = parseCronValue(bounds[1], min, max, names); err != nil {
+					return 0, err
+				}
+				if high < low {
+					return 0, errors.Newf("invalid range %q", expr)
+				}
+			case step == 1:
+				// a single value
+				high = low
+			}
+		}
+		for value := low; value <= high; value += step {
+			set |= 1 << uint(value)
+		}
+	}
+	return set, nil
+}
+
+// parseCronValue parses a number in [min, max] or a name of names.
+func parseCronValue(s string, min, max int, names map[string]int) (int, error) {
+	if value, ok := names[strings.ToLower(s)]; ok {
+		return value, nil
+	}
+	value, err := strconv.Atoi(s)
+	if err != nil || value < min || value > max {
+		return 0, errors.Newf("invalid value %q, expected %d-%d", s, min, max)
+	}
+	return value, nil
+}
@@ -0,0 +1,168 @@
+package rotate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	for _, spec := range []string{"hourly", "@hourly", " HOURLY "} {
+		schedule, err := ParseSchedule(spec)
+		require.NoError(t, err)
+		require.Equal(t, Hourly, schedule)
+	}
+	for _, spec := range []string{"daily", "@daily", "midnight", "@midnight"} {
+		schedule, err := ParseSchedule(spec)
+		require.NoError(t, err)
+		require.Equal(t, Daily, schedule)
+	}
+	schedule, err := ParseSchedule("@weekly")
+	require.NoError(t, err)
+	require.Equal(t, Weekly, schedule)
+
+	schedule, err = ParseSchedule("*/15 1,13 1-7/2 jan-MAR 7")
+	require.NoError(t, err)
+	c := schedule.(*cronSchedule)
+	require.Equal(t, cronField(1|1<<15|1<<30|1<<45), c.minute)
+	require.Equal(t, cronField(1<<1|1<<13), c.hour)
+	require.Equal(t, cronField(1<<1|1<<3|1<<5|1<<7), c.dom)
+	require.Equal(t, cronField(1<<1|1<<2|1<<3), c.month)
+	require.Equal(t, cronField(1), c.dow)
+	require.False(t, c.anyDom)
+	require.False(t, c.anyDow)
+
+	schedule, err = ParseSchedule("5/20 * * * *")
+	require.NoError(t, err)
+	require.Equal(t, cronField(1<<5|1<<25|1<<45), schedule.(*cronSchedule).minute)
+
+	for _, spec := range []string{
+		"",
+		"yearly",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"* * * * foo",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"1-x * * * *",
+	} {
+		_, err = ParseSchedule(spec)
+		require.ErrorIs(t, err, InvalidScheduleError, spec)
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	at := func(value string) time.Time {
+		parsed, err := time.ParseInLocation("2006-01-02 15:04:05", value, shanghai)
+		require.NoError(t, err)
+		return parsed
+	}
+	cases := []struct {
+		spec string
+		now  string
+		want string
+	}{
+		{"hourly", "2024-01-31 14:37:12", "2024-01-31 15:00:00"},
+		{"hourly", "2024-01-31 15:00:00", "2024-01-31 16:00:00"},
+		{"hourly", "2024-12-31 23:59:59", "2025-01-01 00:00:00"},
+		{"daily", "2024-01-31 14:37:12", "2024-02-01 00:00:00"},
+		{"daily", "2024-02-28 00:00:00", "2024-02-29 00:00:00"},
+		// 2024-01-31 is a Wednesday
+		{"weekly", "2024-01-31 14:37:12", "2024-02-05 00:00:00"},
+		{"weekly", "2024-02-05 00:00:00", "2024-02-12 00:00:00"},
+		{"30 2 * * mon-fri", "2024-02-02 03:00:00", "2024-02-05 02:30:00"},
+		{"*/20 * * * *", "2024-01-31 14:37:12", "2024-01-31 14:40:00"},
+		{"0 0 29 2 *", "2023-03-01 00:00:00", "2024-02-29 00:00:00"},
+		// either the day of month or the day of week, 2024-02-03 is a Saturday
+		{"0 0 15 * sat", "2024-02-01 00:00:00", "2024-02-03 00:00:00"},
+		{"0 0 1 jan,jul *", "2024-02-01 00:00:00", "2024-07-01 00:00:00"},
+	}
+	for _, c := range cases {
+		t.Run(c.spec+" "+c.now, func(t *testing.T) {
+			schedule, err := ParseSchedule(c.spec)
+			require.NoError(t, err)
+			next := schedule.Next(at(c.now))
+			require.Equal(t, at(c.want), next)
+			require.Equal(t, shanghai, next.Location())
+		})
+	}
+
+	// the 29th of February
+	schedule, err := ParseSchedule("0 0 29 2 *")
+	require.NoError(t, err)
+	require.Equal(t, at("2028-02-29 00:00:00"), schedule.Next(at("2024-03-01 00:00:00")))
+
+	// never matches
+	for _, spec := range []string{"0 0 30 2 *", "0 0 31 2,4,6 *", "0 0 31 9-11/2 *"} {
+		_, err = ParseSchedule(spec)
+		require.ErrorIs(t, err, InvalidScheduleError, spec)
+	}
+	_, err = ParseSchedule("0 0 31 2 mon")
+	require.NoError(t, err)
+}
+
+func TestScheduleNextDaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database is not available")
+	}
+	// the clocks are set back from 02:00 EDT to 01:00 EST on 2024-11-03
+	first := time.Date(2024, time.November, 3, 1, 30, 0, 0, loc)
+	second := first.Add(time.Hour)
+	require.Equal(t, first.Hour(), second.Hour())
+
+	next := Hourly.Next(first)
+	require.Equal(t, time.Hour/2, next.Sub(first))
+	next = Hourly.Next(second)
+	require.Equal(t, time.Hour/2, next.Sub(second))
+	require.Equal(t, 2, next.Hour())
+
+	// the clocks are set forward from 02:00 EST to 03:00 EDT on 2024-03-10
+	before := time.Date(2024, time.March, 10, 1, 30, 0, 0, loc)
+	next = Hourly.Next(before)
+	require.Equal(t, time.Hour/2, next.Sub(before))
+	require.Equal(t, 3, next.Hour())
+	next = Daily.Next(before)
+	require.Equal(t, time.Date(2024, time.March, 11, 0, 0, 0, 0, loc), next)
+
+	// the skipped 02:30 matches at 03:00 EDT
+	schedule, err := ParseSchedule("30 2 * * *")
+	require.NoError(t, err)
+	next = schedule.Next(time.Date(2024, time.March, 9, 23, 30, 0, 0, loc))
+	require.Equal(t, time.Date(2024, time.March, 10, 3, 0, 0, 0, loc), next)
+	require.Equal(t, time.Date(2024, time.March, 11, 2, 30, 0, 0, loc), schedule.Next(next))
+}
+
+func TestScheduleNextSkippedMidnight(t *testing.T) {
+	cases := []struct {
+		zone string
+		// the day whose midnight is skipped, the clocks are set forward to 01:00
+		year  int
+		month time.Month
+		day   int
+	}{
+		{"America/Santiago", 2024, time.September, 8},
+		{"America/Havana", 2024, time.March, 10},
+		{"Asia/Beirut", 2024, time.March, 31},
+	}
+	for _, c := range cases {
+		t.Run(c.zone, func(t *testing.T) {
+			loc, err := time.LoadLocation(c.zone)
+			if err != nil {
+				t.Skip("time zone database is not available")
+			}
+			// every calendar day has a rotation
+			next := Daily.Next(time.Date(c.year, c.month, c.day-1, 0, 0, 0, 0, loc))
+			require.Equal(t, time.Date(c.year, c.month, c.day, 1, 0, 0, 0, loc), next)
+			require.Equal(t, c.day, next.Day())
+			require.Equal(t, time.Date(c.year, c.month, c.day+1, 0, 0, 0, 0, loc), Daily.Next(next))
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2021-2024 The utility Authors. All rights reserved.
+// Use of this source code is governed by the MIT license that can be found in the
+// LICENSE file
+
+package rotate
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// scheduledRotation is the entry of a rotating file in the scheduler, it is guarded by the
+// mutex of the scheduler.
+type scheduledRotation struct {
+	// at is the time of the next time-based rotation.
+	at time.Time
+	// index is the index of the file in the heap of the scheduler.
+	index int
+}
+
+// rotationHeap is a min-heap of the rotating files ordered by their next rotation time.
+type rotationHeap []*RotatingFile
+
+func (h rotationHeap) Len() int {
+	return len(h)
+}
+
+func (h rotationHeap) Less(i, j int) bool {
+	return h[i].scheduled.at.Before(h[j].scheduled.at)
+}
+
+func (h rotationHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].scheduled.index = i
+	h[j].scheduled.index = j
+}
+
+func (h *rotationHeap) Push(x any) {
+	r := x.(*RotatingFile)
+	r.scheduled.index = len(*h)
+	*h = append(*h, r)
+}
+
+func (h *rotationHeap) Pop() any {
+	old := *h
+	r := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return r
+}
+
+// scheduler triggers the time-based rotations of all the rotating files of the process in
+// a single goroutine, that sleeps until the next rotation time and exits when no file is
+// scheduled, so that a closed file does not leave a goroutine behind.
+type scheduler struct {
+	mtx   sync.Mutex
+	files rotationHeap
+	// running reports whether the goroutine is running.
+	running bool
+	// wakeup wakes the goroutine up when the next rotation time changes.
+	wakeup chan struct{}
+}
+
+// rotationScheduler is the scheduler shared by the rotating files.
+var rotationScheduler = &scheduler{wakeup: make(chan struct{}, 1)}
+
+// contains reports whether r is scheduled.
+func (s *scheduler) contains(r *RotatingFile) bool {
+	index := r.scheduled.index
+	return index < len(s.files) && s.files[index] == r
+}
+
+// schedule schedules the rotation of r at the specified time, it replaces the previous
+// rotation time of r.
+func (s *scheduler) schedule(r *RotatingFile, at time.Time) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	r.scheduled.at = at
+	if s.contains(r) {
+		heap.Fix(&s.files, r.scheduled.index)
+	} else {
+		heap.Push(&s.files, r)
+	}
+	if !s.running {
+		s.running = true
+		go s.run()
+		return
+	}
+	if s.files[0] == r {
+		s.notify()
+	}
+}
+
+// cancel cancels the scheduled rotation of r.
+func (s *scheduler) cancel(r *RotatingFile) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if !s.contains(r) {
+		return
+	}
+	heap.Remove(&s.files, r.scheduled.index)
+	s.notify()
+}
+
+// notify wakes the goroutine up without blocking.
+func (s *scheduler) notify() {
+	select {
+	case s.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// run rotates the files at their rotation time until no file is scheduled.
+func (s *scheduler) run() {
+	for {
+		s.mtx.Lock()
+		if len(s.files) == 0 {
+			s.running = false
+			s.mtx.Unlock()
+			return
+		}
+		r := s.files[0]
+		at := r.scheduled.at
+		wait := time.Until(at)
+		if wait <= 0 {
+			heap.Pop(&s.files)
+		}
+		s.mtx.Unlock()
+
+		if wait <= 0 {
+			// rotate reschedules the file, it must be called without the mutex.
+			r.scheduledRotate(at)
+			continue
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-s.wakeup:
+			timer.Stop()
+		}
+	}
+}
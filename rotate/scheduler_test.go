@@ -0,0 +1,102 @@
+package rotate
+
+import (
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// isScheduled reports whether the time-based rotation of r is scheduled.
+func isScheduled(r *RotatingFile) bool {
+	rotationScheduler.mtx.Lock()
+	defer rotationScheduler.mtx.Unlock()
+	return rotationScheduler.contains(r)
+}
+
+// isRunning reports whether the goroutine of the scheduler is running.
+func isRunning() bool {
+	rotationScheduler.mtx.Lock()
+	defer rotationScheduler.mtx.Unlock()
+	return rotationScheduler.running
+}
+
+func TestScheduler(t *testing.T) {
+	require.Eventually(t, func() bool {
+		return !isRunning()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	first, second := &RotatingFile{}, &RotatingFile{}
+	now := time.Now()
+	rotationScheduler.schedule(first, now.Add(time.Hour))
+	rotationScheduler.schedule(second, now.Add(2*time.Hour))
+	require.True(t, isRunning())
+	require.Equal(t, first, rotationScheduler.files[0])
+
+	// reschedule
+	rotationScheduler.schedule(first, now.Add(3*time.Hour))
+	require.Equal(t, second, rotationScheduler.files[0])
+	require.Equal(t, 2, rotationScheduler.files.Len())
+
+	rotationScheduler.cancel(second)
+	require.False(t, isScheduled(second))
+	require.Equal(t, first, rotationScheduler.files[0])
+	// cancel twice
+	rotationScheduler.cancel(second)
+	require.True(t, isScheduled(first))
+
+	rotationScheduler.cancel(first)
+	require.Eventually(t, func() bool {
+		return !isRunning()
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSchedulerGoroutines(t *testing.T) {
+	require.Eventually(t, func() bool {
+		return !isRunning()
+	}, 5*time.Second, 10*time.Millisecond)
+	baseline := runtime.NumGoroutine()
+
+	testDir := t.TempDir()
+	interval := 300 * time.Millisecond
+	files := make([]*RotatingFile, 0, 4)
+	for index := 0; index < cap(files); index++ {
+		f, err := NewRotatingFile(
+			filepath.Join(testDir, strconv.Itoa(index)+".log"),
+			WithSchedule(intervalSchedule(interval*time.Duration(index+1))),
+			WithCompressLevel(0),
+		)
+		require.NoError(t, err)
+		_, err = f.WriteString("hello")
+		require.NoError(t, err)
+		files = append(files, f)
+	}
+	// a single goroutine for all the files
+	require.True(t, isRunning())
+	require.LessOrEqual(t, runtime.NumGoroutine(), baseline+1)
+
+	// every file is rotated by the shared goroutine
+	for _, f := range files {
+		f := f
+		require.Eventually(t, func() bool {
+			f.mtx.Lock()
+			defer f.mtx.Unlock()
+			return !f.rotatingTime.IsZero()
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	for _, f := range files {
+		require.NoError(t, f.Close())
+	}
+	// the goroutines left by the previous tests may exit meanwhile, Eventually is not used
+	// because it runs the condition in a goroutine
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.LessOrEqual(t, runtime.NumGoroutine(), baseline)
+	require.False(t, isRunning())
+}
@@ -0,0 +1,232 @@
+// Copyright 2021-2024 The utility Authors. All rights reserved.
+// Use of this source code is governed by the MIT license that can be found in the
+// LICENSE file
+
+package rotate
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stkali/utility/errors"
+)
+
+const (
+	// TimestampTemplate names the backups by their rotation time, e.g.
+	// rotating-app.2024-01-02T15-04-05.log, and rotating-app.2024-01-02T15-04-05.1.log when
+	// the file was rotated twice in the same second.
+	TimestampTemplate = "{prefix}{name}.{time:2006-01-02T15-04-05}{seq}{ext}"
+	// LogrotateTemplate names the backups like logrotate: app.log.1 is the newest backup,
+	// and the backups are shifted to app.log.2, app.log.3... at every rotation.
+	LogrotateTemplate = "{name}{ext}.{index}"
+
+	// defaultTimeLayout is the layout of the {time} placeholder without layout.
+	defaultTimeLayout = "2006-01-02T15-04-05"
+)
+
+// segmentKind is the kind of a segment of a backup template.
+type segmentKind uint8
+
+const (
+	literalSegment segmentKind = iota
+	prefixSegment
+	nameSegment
+	extSegment
+	timeSegment
+	seqSegment
+	indexSegment
+)
+
+// templateSegment is a literal text or a placeholder of a backup template, text is the
+// layout of the {time} placeholder.
+type templateSegment struct {
+	kind segmentKind
+	text string
+}
+
+// placeholders maps the placeholders of the backup templates to their kind.
+var placeholders = map[string]segmentKind{
+	"prefix": prefixSegment,
+	"name":   nameSegment,
+	"ext":    extSegment,
+	"time":   timeSegment,
+	"seq":    seqSegment,
+	"index":  indexSegment,
+}
+
+// parseTemplate splits a backup template into segments, it must contain either {time} and
+// {seq}, or {index}.
+func parseTemplate(tmpl string) ([]templateSegment, error) {
+	var segments []templateSegment
+	seen := map[segmentKind]bool{}
+	for rest := tmpl; rest != ""; {
+		start := strings.IndexByte(rest, '{')
+		if start == -1 {
+			segments = append(segments, templateSegment{kind: literalSegment, text: rest})
+			break
+		}
+		if start > 0 {
+			segments = append(segments, templateSegment{kind: literalSegment, text: rest[:start]})
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end == -1 {
+			return nil, errors.Newf("%s %q, err: unclosed placeholder", InvalidBackupTemplateError, tmpl)
+		}
+		end += start
+		// {time:layout} is the only placeholder with an argument
+		name, layout, hasLayout := rest[start+1:end], "", false
+		if index := strings.IndexByte(name, ':'); index != -1 {
+			name, layout, hasLayout = name[:index], name[index+1:], true
+		}
+		kind, ok := placeholders[name]
+		if !ok || hasLayout && (kind != timeSegment || layout == "") {
+			return nil, errors.Newf("%s %q, err: unknown placeholder %q", InvalidBackupTemplateError, tmpl, rest[start:end+1])
+		}
+		if kind == timeSegment && !hasLayout {
+			layout = defaultTimeLayout
+		}
+		if seen[kind] && kind != prefixSegment && kind != nameSegment && kind != extSegment {
+			return nil, errors.Newf("%s %q, err: duplicate placeholder %q", InvalidBackupTemplateError, tmpl, rest[start:end+1])
+		}
+		seen[kind] = true
+		segments = append(segments, templateSegment{kind: kind, text: layout})
+		rest = rest[end+1:]
+	}
+	switch {
+	case strings.ContainsAny(tmpl, `/\`):
+		return nil, errors.Newf("%s %q, err: contains a path separator", InvalidBackupTemplateError, tmpl)
+	case seen[indexSegment] && (seen[timeSegment] || seen[seqSegment]):
+		return nil, errors.Newf("%s %q, err: {index} cannot be used with {time} or {seq}", InvalidBackupTemplateError, tmpl)
+	case seen[timeSegment] != seen[seqSegment]:
+		return nil, errors.Newf("%s %q, err: {time} requires {seq}", InvalidBackupTemplateError, tmpl)
+	case !seen[timeSegment] && !seen[indexSegment]:
+		return nil, errors.Newf("%s %q, err: requires {time} or {index}", InvalidBackupTemplateError, tmpl)
+	}
+	return segments, nil
+}
+
+// backupTemplate is a backup template bound to a rotating file, it renders the names of
+// the backups and parses the rotation time and the sequence back out of them.
+type backupTemplate struct {
+	segments []templateSegment
+	// shift reports whether the template uses {index}, the backups are then shifted.
+	shift bool
+	// layout is the layout of {time} and loc the location of the rotation time.
+	layout string
+	loc    *time.Location
+	// lazy and greedy match the names of the backups, optionally compressed, they differ
+	// by the {time} group that is ambiguous if the layout ends with digits, e.g. ".15".
+	lazy, greedy *regexp.Regexp
+	// timeGroup and seqGroup are the indexes of the groups of {time} and of {seq} or
+	// {index}, 0 if missing.
+	timeGroup, seqGroup int
+}
+
+// newBackupTemplate returns the template of the backups of filename.
+func newBackupTemplate(tmpl, prefix, filename string, loc *time.Location) (*backupTemplate, error) {
+	segments, err := parseTemplate(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	ext := filepath.Ext(filename)
+	values := map[segmentKind]string{
+		prefixSegment: prefix,
+		nameSegment:   strings.TrimSuffix(filename, ext),
+		extSegment:    ext,
+	}
+	t := &backupTemplate{loc: loc}
+	lazy, greedy := &strings.Builder{}, &strings.Builder{}
+	lazy.WriteByte('^')
+	greedy.WriteByte('^')
+	group := 0
+	for _, segment := range segments {
+		switch segment.kind {
+		case timeSegment:
+			group++
+			t.layout, t.timeGroup = segment.text, group
+			lazy.WriteString(`(.+?)`)
+			greedy.WriteString(`(.+)`)
+		case seqSegment:
+			group++
+			t.seqGroup = group
+			lazy.WriteString(`(?:\.(\d+))?`)
+			greedy.WriteString(`(?:\.(\d+))?`)
+		case indexSegment:
+			group++
+			t.seqGroup, t.shift = group, true
+			lazy.WriteString(`(\d+)`)
+			greedy.WriteString(`(\d+)`)
+		default:
+			text := segment.text
+			if segment.kind != literalSegment {
+				text = values[segment.kind]
+			}
+			if text == "" {
+				continue
+			}
+			if last := len(t.segments) - 1; last >= 0 && t.segments[last].kind == literalSegment {
+				t.segments[last].text += text
+			} else {
+				t.segments = append(t.segments, templateSegment{kind: literalSegment, text: text})
+			}
+			lazy.WriteString(regexp.QuoteMeta(text))
+			greedy.WriteString(regexp.QuoteMeta(text))
+			continue
+		}
+		t.segments = append(t.segments, segment)
+	}
+	suffix := `(?:` + regexp.QuoteMeta(compressExtension) + `)?$`
+	t.lazy = regexp.MustCompile(lazy.String() + suffix)
+	t.greedy = regexp.MustCompile(greedy.String() + suffix)
+	return t, nil
+}
+
+// render returns the name of the backup rotated at the specified time with the sequence
+// or the index n, the sequence 0 is omitted.
+func (t *backupTemplate) render(at time.Time, n int) string {
+	var b []byte
+	for _, segment := range t.segments {
+		switch segment.kind {
+		case timeSegment:
+			b = at.In(t.loc).AppendFormat(b, segment.text)
+		case seqSegment:
+			if n > 0 {
+				b = append(b, '.')
+				b = strconv.AppendInt(b, int64(n), 10)
+			}
+		case indexSegment:
+			b = strconv.AppendInt(b, int64(n), 10)
+		default:
+			b = append(b, segment.text...)
+		}
+	}
+	return string(b)
+}
+
+// parse returns the rotation time and the sequence or the index of the backup name, ok
+// is false if the name does not match the template.
+func (t *backupTemplate) parse(name string) (at time.Time, n int, ok bool) {
+	for _, pattern := range []*regexp.Regexp{t.lazy, t.greedy} {
+		groups := pattern.FindStringSubmatch(name)
+		if groups == nil {
+			return at, 0, false
+		}
+		var err error
+		if t.seqGroup != 0 && groups[t.seqGroup] != "" {
+			if n, err = strconv.Atoi(groups[t.seqGroup]); err != nil {
+				continue
+			}
+		}
+		if t.timeGroup == 0 {
+			return at, n, true
+		}
+		if at, err = time.ParseInLocation(t.layout, groups[t.timeGroup], t.loc); err == nil {
+			return at, n, true
+		}
+		n = 0
+	}
+	return at, 0, false
+}
@@ -0,0 +1,106 @@
+package rotate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTemplate(t *testing.T) {
+	for _, tmpl := range []string{
+		TimestampTemplate,
+		LogrotateTemplate,
+		"{name}-{time}{seq}{ext}",
+		"{seq}{time:20060102}-{prefix}{name}{ext}",
+		"backup.{index}.{name}{name}",
+	} {
+		_, err := parseTemplate(tmpl)
+		require.NoError(t, err, tmpl)
+	}
+
+	for _, tmpl := range []string{
+		"",
+		"{name}{ext}",
+		"{name}.{time}",
+		"{name}{seq}",
+		"{name}.{index}{seq}",
+		"{name}.{time}{seq}.{index}",
+		"{name}.{index}.{index}",
+		"{name}.{time}{seq}{time}",
+		"{name}.{index",
+		"{name}.{date}{seq}",
+		"{name}.{time:}{seq}",
+		"{name}.{index:1}",
+		"logs/{name}.{index}",
+		"{name}.{time:2006/01/02}{seq}",
+	} {
+		_, err := parseTemplate(tmpl)
+		require.ErrorIs(t, err, InvalidBackupTemplateError, tmpl)
+	}
+}
+
+func TestBackupTemplate(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	at := time.Date(2024, time.January, 2, 15, 4, 5, 0, shanghai)
+
+	t.Run("timestamp", func(t *testing.T) {
+		tmpl, err := newBackupTemplate(TimestampTemplate, "rotating-", "app.log", time.UTC)
+		require.NoError(t, err)
+		require.False(t, tmpl.shift)
+		require.Equal(t, "rotating-app.2024-01-02T07-04-05.log", tmpl.render(at, 0))
+		require.Equal(t, "rotating-app.2024-01-02T07-04-05.12.log", tmpl.render(at, 12))
+
+		for name, seq := range map[string]int{
+			"rotating-app.2024-01-02T07-04-05.log":       0,
+			"rotating-app.2024-01-02T07-04-05.log.gz":    0,
+			"rotating-app.2024-01-02T07-04-05.12.log":    12,
+			"rotating-app.2024-01-02T07-04-05.12.log.gz": 12,
+		} {
+			parsed, n, ok := tmpl.parse(name)
+			require.True(t, ok, name)
+			require.True(t, at.Equal(parsed), name)
+			require.Equal(t, seq, n, name)
+		}
+		for _, name := range []string{
+			"app.log",
+			"rotating-app.log",
+			"rotating-app.yesterday.log",
+			"rotating-app.2024-01-02T07-04-05.log.zip",
+			"rotating-other.2024-01-02T07-04-05.log",
+		} {
+			_, _, ok := tmpl.parse(name)
+			require.False(t, ok, name)
+		}
+	})
+
+	t.Run("ambiguous layout", func(t *testing.T) {
+		tmpl, err := newBackupTemplate("{name}.{time:2006-01-02.15}{seq}{ext}", "", "app.log", shanghai)
+		require.NoError(t, err)
+		name := tmpl.render(at, 0)
+		require.Equal(t, "app.2024-01-02.15.log", name)
+		parsed, n, ok := tmpl.parse(name)
+		require.True(t, ok)
+		require.Equal(t, at.Truncate(time.Hour), parsed)
+		require.Equal(t, 0, n)
+
+		parsed, n, ok = tmpl.parse(tmpl.render(at, 3))
+		require.True(t, ok)
+		require.Equal(t, at.Truncate(time.Hour), parsed)
+		require.Equal(t, 3, n)
+	})
+
+	t.Run("logrotate", func(t *testing.T) {
+		tmpl, err := newBackupTemplate(LogrotateTemplate, "rotating-", "app", time.Local)
+		require.NoError(t, err)
+		require.True(t, tmpl.shift)
+		require.Equal(t, "app.3", tmpl.render(at, 3))
+		_, n, ok := tmpl.parse("app.3.gz")
+		require.True(t, ok)
+		require.Equal(t, 3, n)
+		_, _, ok = tmpl.parse("app")
+		require.False(t, ok)
+		_, _, ok = tmpl.parse("app.x")
+		require.False(t, ok)
+	})
+}